@@ -0,0 +1,272 @@
+// Package ast is a data representation of a program, Builder
+// is an algebra which builds the nodes, so a tree can be walked,
+// dumped as JSON or replayed into any other algebra
+package ast
+
+import "github.com/havrydotdev/golox/token"
+
+// Node is either an Expr or a Stmt
+type Node interface {
+	node()
+}
+
+type Expr interface {
+	Node
+	expr()
+}
+
+type Stmt interface {
+	Node
+	stmt()
+	located() *Located
+}
+
+// Located is embedded in every statement, Start is the token the
+// statement starts with if the parser located it (see
+// interp.Positioned) and nil otherwise, Replay locates it again
+type Located struct {
+	Start *token.Token
+}
+
+func (l *Located) located() *Located {
+	return l
+}
+
+type (
+	Grouping struct {
+		Expr Expr
+	}
+
+	// Literal has no token, the algebra only gets its value
+	Literal struct {
+		Value any
+	}
+
+	This struct {
+		Keyword token.Token
+	}
+
+	Super struct {
+		Keyword token.Token
+		Method  token.Token
+	}
+
+	Variable struct {
+		Name token.Token
+	}
+
+	Get struct {
+		Object Expr
+		Name   token.Token
+	}
+
+	Unary struct {
+		Op    token.Token
+		Right Expr
+	}
+
+	Assign struct {
+		Name  token.Token
+		Value Expr
+	}
+
+	Binary struct {
+		Op    token.Token
+		Left  Expr
+		Right Expr
+	}
+
+	Logical struct {
+		Op    token.Token
+		Left  Expr
+		Right Expr
+	}
+
+	Call struct {
+		Callee Expr
+		Paren  token.Token
+		Args   []Expr
+	}
+
+	Set struct {
+		Object Expr
+		Name   token.Token
+		Value  Expr
+	}
+
+	List struct {
+		Bracket  token.Token
+		Elements []Expr
+	}
+
+	// Keys[i] is the key of Values[i]
+	Map struct {
+		Brace  token.Token
+		Keys   []Expr
+		Values []Expr
+	}
+
+	Index struct {
+		Object  Expr
+		Bracket token.Token
+		Index   Expr
+	}
+
+	SetIndex struct {
+		Object  Expr
+		Bracket token.Token
+		Index   Expr
+		Value   Expr
+	}
+
+	Lambda struct {
+		Keyword token.Token
+		Params  []token.Token
+		Body    []Stmt
+	}
+)
+
+type (
+	Block struct {
+		Located
+
+		Stmts []Stmt
+	}
+
+	While struct {
+		Located
+
+		Cond Expr
+		Body Stmt
+	}
+
+	// Init, Cond and Incr are nil when omitted
+	For struct {
+		Located
+
+		Init Stmt
+		Cond Expr
+		Incr Expr
+		Body Stmt
+	}
+
+	Break struct {
+		Located
+
+		Keyword token.Token
+	}
+
+	Continue struct {
+		Located
+
+		Keyword token.Token
+	}
+
+	ExprStmt struct {
+		Located
+
+		Expr Expr
+	}
+
+	// Else is nil when omitted
+	If struct {
+		Located
+
+		Cond Expr
+		Then Stmt
+		Else Stmt
+	}
+
+	// Init is nil when omitted
+	Var struct {
+		Located
+
+		Name token.Token
+		Init Expr
+	}
+
+	// Value is nil when omitted
+	Return struct {
+		Located
+
+		Keyword token.Token
+		Value   Expr
+	}
+
+	// Superclass is nil when omitted
+	Class struct {
+		Located
+
+		Name       token.Token
+		Superclass *Variable
+		Methods    []*Function
+	}
+
+	Function struct {
+		Located
+
+		Name   token.Token
+		Params []token.Token
+		Body   []Stmt
+	}
+)
+
+func (*Grouping) node() {}
+func (*Literal) node()  {}
+func (*This) node()     {}
+func (*Super) node()    {}
+func (*Variable) node() {}
+func (*Get) node()      {}
+func (*Unary) node()    {}
+func (*Assign) node()   {}
+func (*Binary) node()   {}
+func (*Logical) node()  {}
+func (*Call) node()     {}
+func (*Set) node()      {}
+func (*List) node()     {}
+func (*Map) node()      {}
+func (*Index) node()    {}
+func (*SetIndex) node() {}
+func (*Lambda) node()   {}
+
+func (*Grouping) expr() {}
+func (*Literal) expr()  {}
+func (*This) expr()     {}
+func (*Super) expr()    {}
+func (*Variable) expr() {}
+func (*Get) expr()      {}
+func (*Unary) expr()    {}
+func (*Assign) expr()   {}
+func (*Binary) expr()   {}
+func (*Logical) expr()  {}
+func (*Call) expr()     {}
+func (*Set) expr()      {}
+func (*List) expr()     {}
+func (*Map) expr()      {}
+func (*Index) expr()    {}
+func (*SetIndex) expr() {}
+func (*Lambda) expr()   {}
+
+func (*Block) node()    {}
+func (*While) node()    {}
+func (*For) node()      {}
+func (*Break) node()    {}
+func (*Continue) node() {}
+func (*ExprStmt) node() {}
+func (*If) node()       {}
+func (*Var) node()      {}
+func (*Return) node()   {}
+func (*Class) node()    {}
+func (*Function) node() {}
+
+func (*Block) stmt()    {}
+func (*While) stmt()    {}
+func (*For) stmt()      {}
+func (*Break) stmt()    {}
+func (*Continue) stmt() {}
+func (*ExprStmt) stmt() {}
+func (*If) stmt()       {}
+func (*Var) stmt()      {}
+func (*Return) stmt()   {}
+func (*Class) stmt()    {}
+func (*Function) stmt() {}
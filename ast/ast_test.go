@@ -0,0 +1,180 @@
+package ast
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	eval "github.com/havrydotdev/golox/evaluator"
+	"github.com/havrydotdev/golox/formatter"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/scanner"
+	"github.com/havrydotdev/golox/token"
+)
+
+func parse(t *testing.T, source string) []Stmt {
+	t.Helper()
+
+	tokens, errs := scanner.New(source).Scan()
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	stmts, errs := parser.New[Expr, Stmt](tokens, Builder{}).Parse()
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	return stmts
+}
+
+// replaying the tree into the formatter has to give
+// the same output as parsing straight into it
+func TestReplay(t *testing.T) {
+	files, err := filepath.Glob("../_examples/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			text, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			source := string(text)
+			scan := scanner.New(source).KeepComments()
+			tokens, errs := scan.Scan()
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+
+			f := formatter.New(tokens, scan.Comments())
+			docs, errs := parser.New[*formatter.Doc, *formatter.Doc](tokens, f).Parse()
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+
+			expected, err := f.Format(docs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f = formatter.New(tokens, scan.Comments())
+			replayed, err := f.Format(Replay(f, parse(t, source)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if replayed != expected {
+				t.Errorf("expected\n%s\ngot\n%s", expected, replayed)
+			}
+		})
+	}
+}
+
+func TestWalk(t *testing.T) {
+	stmts := parse(t, `
+class A < B {
+  f(x) { return fun () { return x + y; }; }
+}
+
+for (var i = 0; i < 1;) print(m[i]);
+`)
+
+	var names []string
+	WalkStmts(stmts, func(node Node) bool {
+		switch n := node.(type) {
+		case *Variable:
+			names = append(names, n.Name.Lexeme)
+		case *Lambda:
+			// the body of the lambda is skipped
+			return false
+		}
+
+		return true
+	})
+
+	expected := []string{"B", "i", "print", "m", "i"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, names)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	out, err := JSON(parse(t, "var a; a = -1;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tree []map[string]any
+	if err := json.Unmarshal(out, &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tree) != 2 || tree[0]["type"] != "Var" || tree[0]["init"] != nil {
+		t.Fatalf("unexpected tree %s", out)
+	}
+
+	assign := tree[1]["expr"].(map[string]any)
+	name := assign["name"].(map[string]any)
+	if assign["type"] != "Assign" || name["kind"] != "Identifier" || name["column"] != float64(8) {
+		t.Errorf("unexpected assignment %v", assign)
+	}
+}
+
+// recorder builds the tree and records the lines of the located statements
+type recorder struct {
+	Builder
+	lines []int
+}
+
+func (r *recorder) At(start token.Token, stmt Stmt) Stmt {
+	r.lines = append(r.lines, start.Line)
+	return stmt
+}
+
+func TestReplayLocates(t *testing.T) {
+	source := "var a = 1;\nfun f() {\n  if (a) print(a);\n}\nwhile (a) a = nil;\n{ f(); }\n"
+	tokens, errs := scanner.New(source).Scan()
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	var parsed, replayed recorder
+	if _, errs := parser.New[Expr, Stmt](tokens, &parsed).Parse(); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	Replay[Expr, Stmt](&replayed, parse(t, source))
+	if len(parsed.lines) == 0 || !slices.Equal(parsed.lines, replayed.lines) {
+		t.Errorf("expected the statements at %v, got %v", parsed.lines, replayed.lines)
+	}
+}
+
+func TestReplayLimits(t *testing.T) {
+	e := eval.New().(*eval.Evaluator)
+	e.SetLimits(eval.Limits{Statements: 5})
+
+	var err error
+	for _, stmt := range Replay[eval.ExpEvaluator, eval.StmtEvaluator](e, parse(t, strings.Repeat("var a = 1;\n", 50))) {
+		if err = stmt.Eval(); err != nil {
+			break
+		}
+	}
+
+	if !errors.As(err, new(*eval.StatementLimitError)) {
+		t.Errorf("expected the replayed statements to be counted, got %v", err)
+	}
+}
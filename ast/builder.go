@@ -0,0 +1,152 @@
+package ast
+
+import (
+	interp "github.com/havrydotdev/golox/interpreter"
+	"github.com/havrydotdev/golox/token"
+)
+
+// Builder is an algebra which builds the tree,
+// omitted parts of the nodes are left nil
+type Builder struct{}
+
+var (
+	_ interp.Alg[Expr, Stmt]  = Builder{}
+	_ interp.Positioned[Stmt] = Builder{}
+)
+
+// At records where the statement starts, so
+// Replay can tell it to the other algebras
+func (Builder) At(start token.Token, stmt Stmt) Stmt {
+	stmt.located().Start = &start
+	return stmt
+}
+
+func (Builder) Grouping(expr Expr) Expr {
+	return &Grouping{Expr: expr}
+}
+
+func (Builder) Literal(value any) Expr {
+	return &Literal{Value: value}
+}
+
+func (Builder) This(keyword token.Token) Expr {
+	return &This{Keyword: keyword}
+}
+
+func (Builder) Super(keyword token.Token, method token.Token) Expr {
+	return &Super{Keyword: keyword, Method: method}
+}
+
+func (Builder) Variable(name token.Token) Expr {
+	return &Variable{Name: name}
+}
+
+func (Builder) Get(name token.Token, expr Expr) Expr {
+	return &Get{Object: expr, Name: name}
+}
+
+func (Builder) Unary(op token.Token, right Expr) Expr {
+	return &Unary{Op: op, Right: right}
+}
+
+func (Builder) Assign(name token.Token, value Expr) Expr {
+	return &Assign{Name: name, Value: value}
+}
+
+func (Builder) Binary(op token.Token, left, right Expr) Expr {
+	return &Binary{Op: op, Left: left, Right: right}
+}
+
+func (Builder) Logical(op token.Token, left, right Expr) Expr {
+	return &Logical{Op: op, Left: left, Right: right}
+}
+
+func (Builder) Call(callee Expr, paren token.Token, args []Expr) Expr {
+	return &Call{Callee: callee, Paren: paren, Args: args}
+}
+
+func (Builder) Set(object Expr, name token.Token, value Expr) Expr {
+	return &Set{Object: object, Name: name, Value: value}
+}
+
+func (Builder) List(bracket token.Token, elements []Expr) Expr {
+	return &List{Bracket: bracket, Elements: elements}
+}
+
+func (Builder) Map(brace token.Token, keys []Expr, values []Expr) Expr {
+	return &Map{Brace: brace, Keys: keys, Values: values}
+}
+
+func (Builder) Index(object Expr, bracket token.Token, index Expr) Expr {
+	return &Index{Object: object, Bracket: bracket, Index: index}
+}
+
+func (Builder) SetIndex(object Expr, bracket token.Token, index Expr, value Expr) Expr {
+	return &SetIndex{Object: object, Bracket: bracket, Index: index, Value: value}
+}
+
+func (Builder) Lambda(keyword token.Token, params []token.Token, body []Stmt) Expr {
+	return &Lambda{Keyword: keyword, Params: params, Body: body}
+}
+
+func (Builder) Block(stmts []Stmt) Stmt {
+	return &Block{Stmts: stmts}
+}
+
+func (Builder) While(cond Expr, body Stmt) Stmt {
+	return &While{Cond: cond, Body: body}
+}
+
+func (Builder) For(init Stmt, cond Expr, incr Expr, body Stmt) Stmt {
+	return &For{Init: init, Cond: cond, Incr: incr, Body: body}
+}
+
+func (Builder) Break(keyword token.Token) Stmt {
+	return &Break{Keyword: keyword}
+}
+
+func (Builder) Continue(keyword token.Token) Stmt {
+	return &Continue{Keyword: keyword}
+}
+
+func (Builder) ExprStatement(expr Expr) Stmt {
+	return &ExprStmt{Expr: expr}
+}
+
+func (Builder) If(cond Expr, then Stmt, _else Stmt) Stmt {
+	return &If{Cond: cond, Then: then, Else: _else}
+}
+
+func (Builder) Var(name token.Token, init Expr) Stmt {
+	return &Var{Name: name, Init: init}
+}
+
+func (Builder) Return(keyword token.Token, value Expr) Stmt {
+	return &Return{Keyword: keyword, Value: value}
+}
+
+// the parser only passes a variable as the superclass
+// and functions as the methods
+func (Builder) Class(name token.Token, superclass Expr, methods []Stmt) Stmt {
+	class := &Class{Name: name}
+	class.Superclass, _ = superclass.(*Variable)
+	for _, method := range methods {
+		if fun, ok := method.(*Function); ok {
+			class.Methods = append(class.Methods, fun)
+		}
+	}
+
+	return class
+}
+
+func (Builder) Function(name token.Token, params []token.Token, body []Stmt) Stmt {
+	return &Function{Name: name, Params: params, Body: body}
+}
+
+func (Builder) NilExpr() Expr {
+	return nil
+}
+
+func (Builder) NilStmt() Stmt {
+	return nil
+}
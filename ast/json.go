@@ -0,0 +1,87 @@
+package ast
+
+import (
+	"encoding/json"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/havrydotdev/golox/token"
+)
+
+// JSON encodes the statements as indented JSON, every node is
+// an object whose "type" is the name of its struct and whose
+// other keys are its fields, omitted parts are null
+func JSON(stmts []Stmt) ([]byte, error) {
+	return json.MarshalIndent(encode(reflect.ValueOf(stmts)), "", "  ")
+}
+
+type tokenJSON struct {
+	Kind   string `json:"kind"`
+	Lexeme string `json:"lexeme"`
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+}
+
+var tokenType = reflect.TypeFor[token.Token]()
+
+// encode walks the nodes with reflection, so
+// new fields and nodes don't need any changes here
+func encode(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+
+		return encode(v.Elem())
+	case reflect.Slice:
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = encode(v.Index(i))
+		}
+
+		return out
+	case reflect.Struct:
+		if v.Type() == tokenType {
+			tok := v.Interface().(token.Token)
+			return tokenJSON{
+				Kind:   tok.Kind.String(),
+				Lexeme: tok.Lexeme,
+				File:   tok.File,
+				Line:   tok.Line,
+				Column: tok.Column,
+				Start:  tok.Start,
+				End:    tok.End,
+			}
+		}
+
+		out := map[string]any{"type": v.Type().Name()}
+		encodeFields(v, out)
+		return out
+	}
+
+	return v.Interface()
+}
+
+// encodeFields puts the fields of the struct into out,
+// the fields of embedded structs like Located are inlined
+func encodeFields(v reflect.Value, out map[string]any) {
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if field.Anonymous {
+			encodeFields(v.Field(i), out)
+			continue
+		}
+
+		out[lowerFirst(field.Name)] = encode(v.Field(i))
+	}
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
@@ -0,0 +1,151 @@
+package ast
+
+import interp "github.com/havrydotdev/golox/interpreter"
+
+// Replay builds the statements again with another algebra,
+// children are built before their parents the same way the
+// parser builds them and nil parts become zero values, the
+// located statements are located again with At
+func Replay[E, S any](alg interp.Alg[E, S], stmts []Stmt) []S {
+	return replayer[E, S]{alg}.stmts(stmts)
+}
+
+// ReplayExpr is Replay for a single expression
+func ReplayExpr[E, S any](alg interp.Alg[E, S], expr Expr) E {
+	return replayer[E, S]{alg}.expr(expr)
+}
+
+type replayer[E, S any] struct {
+	alg interp.Alg[E, S]
+}
+
+func (r replayer[E, S]) expr(expr Expr) E {
+	switch n := expr.(type) {
+	case *Grouping:
+		return r.alg.Grouping(r.expr(n.Expr))
+	case *Literal:
+		return r.alg.Literal(n.Value)
+	case *This:
+		return r.alg.This(n.Keyword)
+	case *Super:
+		return r.alg.Super(n.Keyword, n.Method)
+	case *Variable:
+		return r.alg.Variable(n.Name)
+	case *Get:
+		return r.alg.Get(n.Name, r.expr(n.Object))
+	case *Unary:
+		return r.alg.Unary(n.Op, r.expr(n.Right))
+	case *Assign:
+		return r.alg.Assign(n.Name, r.expr(n.Value))
+	case *Binary:
+		return r.alg.Binary(n.Op, r.expr(n.Left), r.expr(n.Right))
+	case *Logical:
+		return r.alg.Logical(n.Op, r.expr(n.Left), r.expr(n.Right))
+	case *Call:
+		callee := r.expr(n.Callee)
+		return r.alg.Call(callee, n.Paren, r.exprs(n.Args))
+	case *Set:
+		object := r.expr(n.Object)
+		return r.alg.Set(object, n.Name, r.expr(n.Value))
+	case *List:
+		return r.alg.List(n.Bracket, r.exprs(n.Elements))
+	case *Map:
+		keys := make([]E, len(n.Keys))
+		values := make([]E, len(n.Values))
+		for i := range n.Keys {
+			keys[i] = r.expr(n.Keys[i])
+			values[i] = r.expr(n.Values[i])
+		}
+
+		return r.alg.Map(n.Brace, keys, values)
+	case *Index:
+		object := r.expr(n.Object)
+		return r.alg.Index(object, n.Bracket, r.expr(n.Index))
+	case *SetIndex:
+		object := r.expr(n.Object)
+		index := r.expr(n.Index)
+		return r.alg.SetIndex(object, n.Bracket, index, r.expr(n.Value))
+	case *Lambda:
+		return r.alg.Lambda(n.Keyword, n.Params, r.stmts(n.Body))
+	}
+
+	var zero E
+	return zero
+}
+
+// stmt builds the statement and tells the algebra where it
+// starts if it implements interp.Positioned and the parser did
+func (r replayer[E, S]) stmt(stmt Stmt) S {
+	built := r.build(stmt)
+	if alg, ok := r.alg.(interp.Positioned[S]); ok && stmt != nil {
+		if start := stmt.located().Start; start != nil {
+			return alg.At(*start, built)
+		}
+	}
+
+	return built
+}
+
+func (r replayer[E, S]) build(stmt Stmt) S {
+	switch n := stmt.(type) {
+	case *Block:
+		return r.alg.Block(r.stmts(n.Stmts))
+	case *While:
+		cond := r.expr(n.Cond)
+		return r.alg.While(cond, r.stmt(n.Body))
+	case *For:
+		init := r.stmt(n.Init)
+		cond := r.expr(n.Cond)
+		incr := r.expr(n.Incr)
+		return r.alg.For(init, cond, incr, r.stmt(n.Body))
+	case *Break:
+		return r.alg.Break(n.Keyword)
+	case *Continue:
+		return r.alg.Continue(n.Keyword)
+	case *ExprStmt:
+		return r.alg.ExprStatement(r.expr(n.Expr))
+	case *If:
+		cond := r.expr(n.Cond)
+		then := r.stmt(n.Then)
+		return r.alg.If(cond, then, r.stmt(n.Else))
+	case *Var:
+		return r.alg.Var(n.Name, r.expr(n.Init))
+	case *Return:
+		return r.alg.Return(n.Keyword, r.expr(n.Value))
+	case *Class:
+		var superclass E
+		if n.Superclass != nil {
+			superclass = r.expr(n.Superclass)
+		}
+
+		methods := make([]S, len(n.Methods))
+		for i, method := range n.Methods {
+			methods[i] = r.stmt(method)
+		}
+
+		return r.alg.Class(n.Name, superclass, methods)
+	case *Function:
+		return r.alg.Function(n.Name, n.Params, r.stmts(n.Body))
+	}
+
+	var zero S
+	return zero
+}
+
+func (r replayer[E, S]) exprs(exprs []Expr) []E {
+	out := make([]E, len(exprs))
+	for i, expr := range exprs {
+		out[i] = r.expr(expr)
+	}
+
+	return out
+}
+
+func (r replayer[E, S]) stmts(stmts []Stmt) []S {
+	out := make([]S, len(stmts))
+	for i, stmt := range stmts {
+		out[i] = r.stmt(stmt)
+	}
+
+	return out
+}
@@ -0,0 +1,104 @@
+package ast
+
+// Walk calls visit for the node and, if it returns true, walks
+// the children of the node in source order, nil children are skipped
+func Walk(node Node, visit func(node Node) bool) {
+	if !visit(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Grouping:
+		walkExpr(n.Expr, visit)
+	case *Get:
+		walkExpr(n.Object, visit)
+	case *Unary:
+		walkExpr(n.Right, visit)
+	case *Assign:
+		walkExpr(n.Value, visit)
+	case *Binary:
+		walkExpr(n.Left, visit)
+		walkExpr(n.Right, visit)
+	case *Logical:
+		walkExpr(n.Left, visit)
+		walkExpr(n.Right, visit)
+	case *Call:
+		walkExpr(n.Callee, visit)
+		walkExprs(n.Args, visit)
+	case *Set:
+		walkExpr(n.Object, visit)
+		walkExpr(n.Value, visit)
+	case *List:
+		walkExprs(n.Elements, visit)
+	case *Map:
+		for i := range n.Keys {
+			walkExpr(n.Keys[i], visit)
+			walkExpr(n.Values[i], visit)
+		}
+	case *Index:
+		walkExpr(n.Object, visit)
+		walkExpr(n.Index, visit)
+	case *SetIndex:
+		walkExpr(n.Object, visit)
+		walkExpr(n.Index, visit)
+		walkExpr(n.Value, visit)
+	case *Lambda:
+		WalkStmts(n.Body, visit)
+
+	case *Block:
+		WalkStmts(n.Stmts, visit)
+	case *While:
+		walkExpr(n.Cond, visit)
+		walkStmt(n.Body, visit)
+	case *For:
+		walkStmt(n.Init, visit)
+		walkExpr(n.Cond, visit)
+		walkExpr(n.Incr, visit)
+		walkStmt(n.Body, visit)
+	case *ExprStmt:
+		walkExpr(n.Expr, visit)
+	case *If:
+		walkExpr(n.Cond, visit)
+		walkStmt(n.Then, visit)
+		walkStmt(n.Else, visit)
+	case *Var:
+		walkExpr(n.Init, visit)
+	case *Return:
+		walkExpr(n.Value, visit)
+	case *Class:
+		if n.Superclass != nil {
+			Walk(n.Superclass, visit)
+		}
+
+		for _, method := range n.Methods {
+			Walk(method, visit)
+		}
+	case *Function:
+		WalkStmts(n.Body, visit)
+	}
+}
+
+// WalkStmts walks every statement of a program or a body
+func WalkStmts(stmts []Stmt, visit func(node Node) bool) {
+	for _, stmt := range stmts {
+		walkStmt(stmt, visit)
+	}
+}
+
+func walkExpr(expr Expr, visit func(node Node) bool) {
+	if expr != nil {
+		Walk(expr, visit)
+	}
+}
+
+func walkExprs(exprs []Expr, visit func(node Node) bool) {
+	for _, expr := range exprs {
+		walkExpr(expr, visit)
+	}
+}
+
+func walkStmt(stmt Stmt, visit func(node Node) bool) {
+	if stmt != nil {
+		Walk(stmt, visit)
+	}
+}
@@ -0,0 +1,337 @@
+package golox
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+
+	eval "github.com/havrydotdev/golox/evaluator"
+	"github.com/havrydotdev/golox/lox"
+)
+
+var errorType = reflect.TypeFor[error]()
+
+// Register binds the Go function or pointer to a struct as a global,
+// the arguments of functions are converted from lox values to the
+// types of their parameters, the results are converted back and a
+// trailing error result fails the call with a runtime error.
+// Structs become objects, lox code reads and sets their exported
+// fields and calls their exported methods by their Go names
+func (e *Engine) Register(name string, value any) error {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Func && !(v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct) {
+		return fmt.Errorf("can only register functions and pointers to structs, got %T", value)
+	}
+
+	return e.SetGlobal(name, value)
+}
+
+// toLox converts the Go value into a lox value, lox values are kept
+// as they are, numbers become float32, slices and arrays lists, maps
+// lox maps, functions natives and structs objects
+func toLox(value any) (any, error) {
+	return valueToLox(reflect.ValueOf(value))
+}
+
+func valueToLox(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	if v.CanInterface() {
+		switch value := v.Interface().(type) {
+		case bool, string, float32, *lox.List, *lox.Map, eval.Callable, eval.Object, *eval.Instance:
+			return value, nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float32(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float32(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return float32(v.Float()), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		return valueToLox(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+
+		elements := make([]any, v.Len())
+		for i := range elements {
+			element, err := valueToLox(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			elements[i] = element
+		}
+
+		return lox.NewList(elements), nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		m := lox.NewMap()
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := valueToLox(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+
+			val, err := valueToLox(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+
+			if err := m.Set(key, val); err != nil {
+				return nil, err
+			}
+		}
+
+		return m, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		return native(v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		if v.Elem().Kind() == reflect.Struct {
+			return object{v.Interface()}, nil
+		}
+	case reflect.Struct:
+		// the object needs a pointer, so it works on a copy
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		return object{ptr.Interface()}, nil
+	}
+
+	return nil, fmt.Errorf("%s is not a lox value", v.Type())
+}
+
+// fromLox converts the lox value into a Go value of the type,
+// numbers which are converted to integers have to be whole
+func fromLox(value any, t reflect.Type) (reflect.Value, error) {
+	if obj, ok := value.(object); ok {
+		// the pointer is what Go code knows, not the object
+		value = obj.ptr
+	}
+
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+	} else if reflect.TypeOf(value).AssignableTo(t) {
+		return reflect.ValueOf(value), nil
+	}
+
+	v := reflect.New(t).Elem()
+	switch value := value.(type) {
+	case float32:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n := int64(value)
+			if float32(n) == value && !v.OverflowInt(n) {
+				v.SetInt(n)
+				return v, nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n := uint64(value)
+			if value >= 0 && float32(n) == value && !v.OverflowUint(n) {
+				v.SetUint(n)
+				return v, nil
+			}
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(float64(value))
+			return v, nil
+		}
+	case bool:
+		if t.Kind() == reflect.Bool {
+			v.SetBool(value)
+			return v, nil
+		}
+	case string:
+		if t.Kind() == reflect.String {
+			v.SetString(value)
+			return v, nil
+		}
+	case *lox.List:
+		if t.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(t, len(value.Elements), len(value.Elements)))
+			for i, element := range value.Elements {
+				elem, err := fromLox(element, t.Elem())
+				if err != nil {
+					return v, err
+				}
+
+				v.Index(i).Set(elem)
+			}
+
+			return v, nil
+		}
+	case *lox.Map:
+		if t.Kind() == reflect.Map {
+			v.Set(reflect.MakeMapWithSize(t, value.Len()))
+			values := value.Values()
+			for i, key := range value.Keys() {
+				k, err := fromLox(key, t.Key())
+				if err != nil {
+					return v, err
+				}
+
+				val, err := fromLox(values[i], t.Elem())
+				if err != nil {
+					return v, err
+				}
+
+				v.SetMapIndex(k, val)
+			}
+
+			return v, nil
+		}
+	}
+
+	// structs are passed by value from the pointer of their object
+	if ptr := reflect.ValueOf(value); ptr.Kind() == reflect.Pointer && ptr.Type().Elem() == t {
+		return ptr.Elem(), nil
+	}
+
+	return v, fmt.Errorf("expected %s, got %s", t, lox.Stringify(value))
+}
+
+// native wraps the function into a native, it fails
+// for functions lox can't call or whose results it can't use
+func native(fn reflect.Value) (eval.Callable, error) {
+	t := fn.Type()
+	if t.IsVariadic() || t.NumIn() > math.MaxUint8 {
+		return nil, fmt.Errorf("can't bind %s, variadic functions and more than %d parameters aren't supported", t, math.MaxUint8)
+	}
+
+	results := t.NumOut()
+	if results > 2 || (results == 2 && t.Out(1) != errorType) {
+		return nil, fmt.Errorf("can't bind %s, it has to return at most a value and an error", t)
+	}
+
+	return eval.NewNativeFun(uint8(t.NumIn()), func(_ *eval.Evaluator, args []any) (any, error) {
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			v, err := fromLox(arg, t.In(i))
+			if err != nil {
+				return nil, fmt.Errorf("argument %d: %w", i+1, err)
+			}
+
+			in[i] = v
+		}
+
+		out, err := call(fn, in)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(out) != 0 && t.Out(len(out)-1) == errorType {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return nil, err
+			}
+
+			out = out[:len(out)-1]
+		}
+
+		if len(out) == 0 {
+			return nil, nil
+		}
+
+		return valueToLox(out[0])
+	}), nil
+}
+
+// call calls the function, a panic fails the
+// call instead of crashing the host
+func call(fn reflect.Value, in []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return fn.Call(in), nil
+}
+
+// object exposes a pointer to a struct to lox, objects
+// of the same pointer are equal like instances are
+type object struct {
+	ptr any
+}
+
+func (o object) String() string {
+	return reflect.TypeOf(o.ptr).Elem().Name() + " instance"
+}
+
+// Get reads the exported field or binds the exported method,
+// methods with a pointer receiver are found too
+func (o object) Get(name string) (any, bool) {
+	ptr := reflect.ValueOf(o.ptr)
+	if method := ptr.MethodByName(name); method.IsValid() {
+		fn, err := native(method)
+		return fn, err == nil
+	}
+
+	field, ok := ptr.Type().Elem().FieldByName(name)
+	if !ok || !field.IsExported() {
+		return nil, false
+	}
+
+	v, err := ptr.Elem().FieldByIndexErr(field.Index)
+	if err != nil {
+		// the field is in an embedded struct whose pointer is nil
+		return nil, false
+	}
+
+	if v.Kind() == reflect.Struct {
+		// nested structs are shared, so setting their fields
+		// changes the struct which contains them
+		return object{v.Addr().Interface()}, true
+	}
+
+	val, err := valueToLox(v)
+	return val, err == nil
+}
+
+func (o object) Set(name string, value any) error {
+	ptr := reflect.ValueOf(o.ptr)
+	field, ok := ptr.Type().Elem().FieldByName(name)
+	if !ok || !field.IsExported() {
+		return errors.New("undefined field " + name)
+	}
+
+	v, err := fromLox(value, field.Type)
+	if err != nil {
+		return fmt.Errorf("field %s: %w", name, err)
+	}
+
+	dst, err := ptr.Elem().FieldByIndexErr(field.Index)
+	if err != nil {
+		return fmt.Errorf("field %s: %w", name, err)
+	}
+
+	dst.Set(v)
+	return nil
+}
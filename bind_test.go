@@ -0,0 +1,112 @@
+package golox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X, Y   float64
+	Label  string
+	hidden int
+}
+
+func (p *point) Move(dx, dy float64) {
+	p.X += dx
+	p.Y += dy
+}
+
+func (p point) Sum() float64 {
+	return p.X + p.Y
+}
+
+func TestRegister(t *testing.T) {
+	var out bytes.Buffer
+	e := New()
+	e.SetStdout(&out)
+
+	origin := &point{Label: "origin"}
+	register := map[string]any{
+		"origin": origin,
+		"repeat": strings.Repeat,
+		"sum": func(xs []int) int {
+			total := 0
+			for _, x := range xs {
+				total += x
+			}
+
+			return total
+		},
+		"divide": func(a, b float64) (float64, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+
+			return a / b, nil
+		},
+		"shift": func(p point, dx float64) point {
+			p.X += dx
+			return p
+		},
+	}
+
+	for name, value := range register {
+		if err := e.Register(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := e.Run(context.Background(), `
+origin.Move(1, 2);
+origin.Label = "moved";
+print(origin.Sum());
+print(repeat("ab", 3));
+print(sum([1, 2, 3]));
+print(divide(1, 4));
+var shifted = shift(origin, 10);
+print(shifted.X);
+print(origin.X);
+print(origin);
+var seen = {};
+seen[origin] = "seen";
+print(seen[origin]);
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := fmt.Sprintln("3\nababab\n6\n0.25\n11\n1\npoint instance\nseen")
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	if *origin != (point{X: 1, Y: 2, Label: "moved"}) {
+		t.Errorf("unexpected point %+v", origin)
+	}
+
+	for source, message := range map[string]string{
+		"divide(1, 0);":         "division by zero",
+		"sum([1.5]);":           "argument 1: expected int, got 1.5",
+		`origin.X = "a";`:       "field X: expected float64, got a",
+		"origin.hidden;":        "undefined property hidden",
+		`repeat("a", -1, nil);`: "expected 2 arguments, got 3",
+		`repeat("a", -1);`:      "panic: strings: negative Repeat count",
+	} {
+		var runtimeErr *RuntimeError
+		if err := e.Run(context.Background(), source); !errors.As(err, &runtimeErr) || runtimeErr.Diagnostic.Message != message {
+			t.Errorf("%s: expected %q, got %v", source, message, err)
+		}
+	}
+
+	if err := e.Register("n", 1); err == nil {
+		t.Error("only functions and structs can be registered")
+	}
+
+	if err := e.Register("printf", fmt.Printf); err == nil {
+		t.Error("variadic functions can't be registered")
+	}
+}
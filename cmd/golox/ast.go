@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/havrydotdev/golox/ast"
+	"github.com/havrydotdev/golox/diagnostics"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/scanner"
+)
+
+// dumpAST prints the syntax tree of the file
+// as JSON, it returns the exit code
+func dumpAST(args []string) int {
+	flags := flag.NewFlagSet("ast", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: golox ast file")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	fileName := flags.Arg(0)
+	text, err := os.ReadFile(fileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	source := string(text)
+	stmts, errs := parseSource(fileName, source)
+	if len(errs) != 0 {
+		renderer := diagnostics.NewRenderer(os.Stderr, source)
+		for _, err := range errs {
+			renderer.Render(diagnostics.From(err))
+		}
+
+		return 1
+	}
+
+	out, err := ast.JSON(stmts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	fmt.Println(string(out))
+	return 0
+}
+
+// parseSource builds the tree of the source,
+// which is only usable without errors
+func parseSource(fileName, source string) ([]ast.Stmt, []error) {
+	tokens, errs := scanner.NewFile(fileName, source).Scan()
+	if len(errs) != 0 {
+		return nil, errs
+	}
+
+	return parser.New[ast.Expr, ast.Stmt](tokens, ast.Builder{}).Parse()
+}
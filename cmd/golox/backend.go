@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/havrydotdev/golox/compiler"
+	"github.com/havrydotdev/golox/diagnostics"
+	eval "github.com/havrydotdev/golox/evaluator"
+	"github.com/havrydotdev/golox/lox"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/resolver"
+	"github.com/havrydotdev/golox/token"
+	"github.com/havrydotdev/golox/vm"
+)
+
+// backend runs programs, its state
+// (globals, definitions) is kept between the runs
+type backend interface {
+	// exec runs the tokens, it stops at the first stage
+	// which fails or at the first runtime error and returns its errors
+	exec(tokens []token.Token) []error
+	// echo evaluates and prints the value of the tokens if
+	// they are a single expression, it reports whether they were
+	echo(source string, tokens []token.Token) bool
+}
+
+// newBackend creates the backend by its name, tree or vm,
+// the programs and the REPL print to out
+func newBackend(name string, out io.Writer) (backend, error) {
+	switch name {
+	case "tree":
+		return newTreeBackend(out), nil
+	case "vm":
+		return newVMBackend(out), nil
+	default:
+		return nil, fmt.Errorf("unknown backend %s", name)
+	}
+}
+
+// run executes the tokens and renders their errors,
+// source is only used to render the diagnostics
+func run(b backend, source string, tokens []token.Token) {
+	printErrors(source, b.exec(tokens))
+}
+
+type treeBackend struct {
+	evaluator *eval.Evaluator
+}
+
+func newTreeBackend(out io.Writer) *treeBackend {
+	e := eval.New().(*eval.Evaluator)
+	e.SetStdout(out)
+
+	return &treeBackend{e}
+}
+
+func (b *treeBackend) exec(tokens []token.Token) []error {
+	res := resolver.New[eval.ExpEvaluator, eval.StmtEvaluator](b.evaluator)
+	nodes, errs := parser.New(tokens, res).Parse()
+	if len(errs) != 0 {
+		return errs
+	}
+
+	exprs, errs := res.Resolve(nodes)
+	if len(errs) != 0 {
+		return errs
+	}
+
+	for _, expr := range exprs {
+		if err := expr.Eval(); err != nil {
+			return []error{err}
+		}
+	}
+
+	return nil
+}
+
+func (b *treeBackend) echo(source string, tokens []token.Token) bool {
+	res := resolver.New[eval.ExpEvaluator, eval.StmtEvaluator](b.evaluator)
+	node, err := parser.New(tokens, res).ParseExpression()
+	if err != nil {
+		return false
+	}
+
+	expr, errs := res.ResolveExpression(node)
+	if printErrors(source, errs) {
+		return true
+	}
+
+	val, err := expr.Eval()
+	if err != nil {
+		printErrors(source, []error{err})
+		return true
+	}
+
+	fmt.Fprintln(b.evaluator.Stdout(), lox.Stringify(val))
+	return true
+}
+
+type vmBackend struct {
+	vm *vm.VM
+}
+
+func newVMBackend(out io.Writer) *vmBackend {
+	machine := vm.New()
+	machine.SetStdout(out)
+
+	return &vmBackend{machine}
+}
+
+func (b *vmBackend) exec(tokens []token.Token) []error {
+	comp := compiler.New()
+	res := resolver.New(comp)
+	nodes, errs := parser.New(tokens, res).Parse()
+	if len(errs) != 0 {
+		return errs
+	}
+
+	code, errs := res.Resolve(nodes)
+	if len(errs) != 0 {
+		return errs
+	}
+
+	script, errs := comp.Compile(code)
+	if len(errs) != 0 {
+		return errs
+	}
+
+	if _, err := b.vm.Run(script); err != nil {
+		return []error{err}
+	}
+
+	return nil
+}
+
+func (b *vmBackend) echo(source string, tokens []token.Token) bool {
+	comp := compiler.New()
+	res := resolver.New(comp)
+	node, err := parser.New(tokens, res).ParseExpression()
+	if err != nil {
+		return false
+	}
+
+	code, errs := res.ResolveExpression(node)
+	if printErrors(source, errs) {
+		return true
+	}
+
+	script, errs := comp.CompileExpression(code)
+	if printErrors(source, errs) {
+		return true
+	}
+
+	val, err := b.vm.Run(script)
+	if err != nil {
+		printErrors(source, []error{err})
+		return true
+	}
+
+	fmt.Fprintln(b.vm.Stdout(), lox.Stringify(val))
+	return true
+}
+
+// printErrors renders the errors with the
+// source and reports whether there were any
+func printErrors(source string, errs []error) bool {
+	renderer := diagnostics.NewRenderer(os.Stderr, source)
+	for _, err := range errs {
+		renderer.Render(diagnostics.From(err))
+	}
+
+	return len(errs) != 0
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/havrydotdev/golox/diagnostics"
+	interp "github.com/havrydotdev/golox/interpreter"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/resolver"
+	"github.com/havrydotdev/golox/scanner"
+	"github.com/havrydotdev/golox/token"
+)
+
+// check reports the scanner, parser and resolver errors of
+// the files without running them, it returns the exit code
+func check(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	format := flags.String("format", "text", "output format, text or json")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: golox check [-format text|json] files...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || (*format != "text" && *format != "json") {
+		flags.Usage()
+		return 2
+	}
+
+	return checkFiles(os.Stdout, os.Stderr, *format, flags.Args())
+}
+
+// checkFiles checks the files in order, a file which can't be read
+// is reported like its errors would be and the others are still
+// checked, the JSON records are written to out and the text to errOut
+func checkFiles(out, errOut io.Writer, format string, fileNames []string) int {
+	records := []diagnostics.Record{}
+	report := func(renderer *diagnostics.Renderer, d diagnostics.Diagnostic) {
+		if format == "json" {
+			records = append(records, d.Record())
+		} else {
+			renderer.Render(d)
+		}
+	}
+
+	failed, unreadable := false, false
+	for _, fileName := range fileNames {
+		text, err := os.ReadFile(fileName)
+		if err != nil {
+			unreadable = true
+			report(diagnostics.NewRenderer(errOut, ""), diagnostics.Diagnostic{
+				Code:    diagnostics.CodeRead,
+				Token:   token.Token{File: fileName},
+				Message: err.Error(),
+			})
+			continue
+		}
+
+		source := string(text)
+		errs := checkSource(fileName, source)
+		failed = failed || len(errs) != 0
+
+		renderer := diagnostics.NewRenderer(errOut, source)
+		for _, err := range errs {
+			report(renderer, diagnostics.From(err))
+		}
+	}
+
+	if format == "json" {
+		text, _ := json.MarshalIndent(records, "", "  ")
+		fmt.Fprintln(out, string(text))
+	}
+
+	switch {
+	case unreadable:
+		return 2
+	case failed:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// checkSource parses and resolves the source with an
+// algebra which builds nothing, so none of it runs
+func checkSource(fileName, source string) []error {
+	tokens, errs := scanner.NewFile(fileName, source).Scan()
+	if len(errs) != 0 {
+		return errs
+	}
+
+	res := resolver.New[struct{}, struct{}](interp.Nop{})
+	nodes, errs := parser.New(tokens, res).Parse()
+	if len(errs) != 0 {
+		return errs
+	}
+
+	_, errs = res.Resolve(nodes)
+	return errs
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/havrydotdev/golox/diagnostics"
+)
+
+func TestCheckSource(t *testing.T) {
+	tests := map[string]int{
+		// nothing runs, so runtime errors are not reported
+		"print(undefined); clock(1, 2);": 0,
+		"var a = 1 @ 2; var b = \"c":     2,
+		"var a = ; print(a)":             2,
+		"return 1; { var a = a; }":       2,
+	}
+
+	for source, count := range tests {
+		t.Run(source, func(t *testing.T) {
+			if errs := checkSource("main.lox", source); len(errs) != count {
+				t.Errorf("expected %d errors, got %v", count, errs)
+			}
+		})
+	}
+}
+
+func TestCheckUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "broken.lox")
+	if err := os.WriteFile(broken, []byte("var a = ;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	missing := filepath.Join(dir, "missing.lox")
+	if code := checkFiles(&out, io.Discard, "json", []string{missing, broken}); code != 2 {
+		t.Errorf("expected exit code 2, got %d", code)
+	}
+
+	var records []diagnostics.Record
+	if err := json.Unmarshal(out.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file after the missing one is still checked
+	if len(records) != 2 || records[0].File != missing || records[0].Code != diagnostics.CodeRead ||
+		records[1].File != broken || records[1].Code != diagnostics.CodeSyntax {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
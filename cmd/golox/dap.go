@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/havrydotdev/golox/dap"
+)
+
+// debug runs the debug adapter over stdin and stdout
+// until the editor disconnects, it returns the exit code
+func debug(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "usage: golox dap")
+		return 2
+	}
+
+	// what the program prints is sent to the editor
+	// as output events, stdout only carries the protocol
+	if err := dap.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
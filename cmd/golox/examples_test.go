@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// every example is run with its expectations on both backends
+func TestExamples(t *testing.T) {
+	files, err := testFiles([]string{"../../_examples"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"tree", "vm"} {
+		for _, fileName := range files {
+			problems, err := testFile(name, fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, problem := range problems {
+				t.Errorf("%s %s: %s", name, fileName, problem)
+			}
+		}
+	}
+}
+
+func TestRunTestFiles(t *testing.T) {
+	var out strings.Builder
+	files := []string{"../../_examples/missing.lox", "../../_examples/maps.lox"}
+	if code := runTestFiles(&out, "tree", files); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+
+	// the missing file fails and the run goes on to the summary
+	for _, line := range []string{"FAIL " + files[0], "PASS " + files[1], "1 passed, 1 failed"} {
+		if !strings.Contains(out.String(), fmt.Sprintln(line)) {
+			t.Errorf("expected %q in the output %q", line, out.String())
+		}
+	}
+}
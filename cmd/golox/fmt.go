@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/havrydotdev/golox/diagnostics"
+	"github.com/havrydotdev/golox/formatter"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/scanner"
+	"github.com/havrydotdev/golox/token"
+)
+
+// format reprints the files canonically, to stdout
+// or back into the files with -w, it returns the exit code
+func format(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result back to the files instead of printing it")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: golox fmt [-w] files...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	// files which can't be read or written are
+	// reported and the others are still formatted
+	code := 0
+	for _, fileName := range flags.Args() {
+		text, err := os.ReadFile(fileName)
+		if err != nil {
+			reportReadError(fileName, err)
+			code = 2
+			continue
+		}
+
+		source := string(text)
+		formatted, errs := formatSource(fileName, source)
+		if len(errs) != 0 {
+			renderer := diagnostics.NewRenderer(os.Stderr, source)
+			for _, err := range errs {
+				renderer.Render(diagnostics.From(err))
+			}
+
+			code = max(code, 1)
+			continue
+		}
+
+		if !*write {
+			fmt.Print(formatted)
+			continue
+		}
+
+		if formatted != source {
+			if err := os.WriteFile(fileName, []byte(formatted), 0o644); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				code = 2
+			}
+		}
+	}
+
+	return code
+}
+
+// reportReadError renders the error like check does
+func reportReadError(fileName string, err error) {
+	diagnostics.NewRenderer(os.Stderr, "").Render(diagnostics.Diagnostic{
+		Code:    diagnostics.CodeRead,
+		Token:   token.Token{File: fileName},
+		Message: err.Error(),
+	})
+}
+
+// formatSource only formats code which parses without errors
+func formatSource(fileName, source string) (string, []error) {
+	scan := scanner.NewFile(fileName, source).KeepComments()
+	tokens, errs := scan.Scan()
+	if len(errs) != 0 {
+		return "", errs
+	}
+
+	f := formatter.New(tokens, scan.Comments())
+	stmts, errs := parser.New[*formatter.Doc, *formatter.Doc](tokens, f).Parse()
+	if len(errs) != 0 {
+		return "", errs
+	}
+
+	formatted, err := f.Format(stmts)
+	if err != nil {
+		return "", []error{err}
+	}
+
+	return formatted, nil
+}
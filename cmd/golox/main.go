@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/havrydotdev/golox/scanner"
+	"github.com/havrydotdev/golox/token"
+)
+
+var backendName = flag.String("backend", "tree", "backend which runs the code, tree or vm")
+
+// commands run as "golox <command> args...", their result
+// is the exit code, without one golox runs a file or the REPL
+var commands = map[string]func(args []string) int{
+	"ast":   dumpAST,
+	"check": check,
+	"dap":   debug,
+	"fmt":   format,
+	"lsp":   serveLSP,
+	"test":  runTests,
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
+	flag.Parse()
+
+	// the commands have their own flags, which go after their name
+	if _, ok := commands[flag.Arg(0)]; ok {
+		fmt.Fprintf(os.Stderr, "usage: golox %s [flags] args..., flags go after the command\n", flag.Arg(0))
+		os.Exit(2)
+	}
+
+	b, err := newBackend(*backendName, os.Stdout)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	if flag.NArg() > 0 {
+		fileName := flag.Arg(0)
+		text, err := os.ReadFile(fileName)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		// broken tokens never reach the parser
+		source := string(text)
+		tokens, errs := scanner.NewFile(fileName, source).Scan()
+		if printErrors(source, errs) {
+			return
+		}
+
+		run(b, source, tokens)
+	} else {
+		repl(b, os.Stdin, os.Stdout)
+	}
+}
+
+// repl reads the input until the brackets of every kind are balanced
+// and the strings are closed,
+// every input runs on the same backend so the state is kept, the
+// prompts are printed to out, which should be the one of the backend
+func repl(b backend, in io.Reader, out io.Writer) {
+	fmt.Fprintln(out, "Welcome to GoLox (version 0.0.1)!")
+
+	reader := bufio.NewReader(in)
+	var input strings.Builder
+	for {
+		if input.Len() == 0 {
+			fmt.Fprint(out, "> ")
+		} else {
+			fmt.Fprint(out, "... ")
+		}
+
+		line, err := reader.ReadString('\n')
+		if err == io.EOF && line == "" {
+			fmt.Fprintln(out)
+			return
+		} else if err != nil && err != io.EOF {
+			fmt.Fprintln(out, err)
+			return
+		}
+
+		input.WriteString(line)
+
+		source := input.String()
+		tokens, errs := scanner.New(source).Scan()
+		if unterminated(source, errs) || (len(errs) == 0 && unbalanced(tokens)) {
+			continue
+		}
+
+		if printErrors(source, errs) {
+			input.Reset()
+			continue
+		}
+
+		input.Reset()
+		if !b.echo(source, tokens) {
+			run(b, source, tokens)
+		}
+	}
+}
+
+// unterminated reports whether the input ends inside a
+// string, the rest of the string may be on the next lines
+func unterminated(source string, errs []error) bool {
+	var scanErr scanner.Error
+	if len(errs) == 0 || !errors.As(errs[len(errs)-1], &scanErr) {
+		return false
+	}
+
+	return strings.HasPrefix(scanErr.Token.Lexeme, `"`) && scanErr.Token.End == len(source)
+}
+
+// unbalanced reports whether there are braces, parentheses
+// or brackets which were opened but not closed yet
+func unbalanced(tokens []token.Token) bool {
+	depth := 0
+	for _, tok := range tokens {
+		switch tok.Kind {
+		case token.LeftBrace, token.LeftParen, token.LeftBracket:
+			depth++
+		case token.RightBrace, token.RightParen, token.RightBracket:
+			depth--
+		}
+	}
+
+	return depth > 0
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestREPL(t *testing.T) {
+	tests := map[string]string{
+		"var xs = [\n  1,\n  {\"a\": 2}\n];\nprint(len(xs));\n": "> ... ... ... > 2\n> \n",
+		"print(\"a\nb\");\n": "> ... a\nb\n> \n",
+	}
+
+	for _, name := range []string{"tree", "vm"} {
+		for input, prompts := range tests {
+			var out bytes.Buffer
+			b, err := newBackend(name, &out)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			repl(b, strings.NewReader(input), &out)
+			expected := "Welcome to GoLox (version 0.0.1)!\n" + prompts
+			if out.String() != expected {
+				t.Errorf("%s %q: expected %q, got %q", name, input, expected, out.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/havrydotdev/golox/diagnostics"
+	"github.com/havrydotdev/golox/scanner"
+	"github.com/havrydotdev/golox/token"
+)
+
+const (
+	expectOutput       = "// expect: "
+	expectRuntimeError = "// expect runtime error: "
+)
+
+// expectation is a line a file should print or the runtime
+// error it should fail with, both are written in its comments
+type expectation struct {
+	line int
+	text string
+}
+
+// expectations reads the comments of the file, every printed
+// line is expected with "// expect: text" and the runtime
+// error with "// expect runtime error: message"
+func expectations(comments []token.Token) (output []expectation, runtimeError *expectation) {
+	for _, comment := range comments {
+		if text, ok := strings.CutPrefix(comment.Lexeme, expectOutput); ok {
+			output = append(output, expectation{comment.Line, text})
+		} else if text, ok := strings.CutPrefix(comment.Lexeme, expectRuntimeError); ok {
+			runtimeError = &expectation{comment.Line, text}
+		}
+	}
+
+	return output, runtimeError
+}
+
+// runTests runs the lox files, and the ones inside the
+// directories, against their expectations, it returns the exit code
+func runTests(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	name := flags.String("backend", "tree", "backend which runs the code, tree or vm")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: golox test [-backend tree|vm] files or directories...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	files, err := testFiles(flags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	return runTestFiles(os.Stdout, *name, files)
+}
+
+// runTestFiles prints the result of every file and a summary to
+// out, a file which can't be run fails and the others still run
+func runTestFiles(out io.Writer, backendName string, files []string) int {
+	passed, failed := 0, 0
+	for _, fileName := range files {
+		problems, err := testFile(backendName, fileName)
+		if err != nil {
+			problems = []string{err.Error()}
+		}
+
+		if len(problems) == 0 {
+			passed++
+			fmt.Fprintf(out, "PASS %s\n", fileName)
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(out, "FAIL %s\n", fileName)
+		for _, problem := range problems {
+			fmt.Fprintf(out, "    %s\n", problem)
+		}
+	}
+
+	fmt.Fprintf(out, "%d passed, %d failed\n", passed, failed)
+	if failed != 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// testFiles expands the directories into the lox files
+// they contain, files given by name are kept as they are
+func testFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(fileName string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && filepath.Ext(fileName) == ".lox" {
+				files = append(files, fileName)
+			}
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// testFile runs the file on a new backend and describes every
+// way it didn't meet its expectations, the error is only set
+// if the file couldn't be run at all
+func testFile(backendName, fileName string) ([]string, error) {
+	var out strings.Builder
+	b, err := newBackend(backendName, &out)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	scan := scanner.NewFile(fileName, string(text)).KeepComments()
+	tokens, errs := scan.Scan()
+	output, runtimeError := expectations(scan.Comments())
+
+	if len(errs) == 0 {
+		errs = b.exec(tokens)
+	}
+
+	var problems []string
+	printed := out.String()
+	lines := strings.Split(strings.TrimSuffix(printed, "\n"), "\n")
+	if printed == "" {
+		lines = nil
+	}
+
+	for i, expected := range output {
+		if i >= len(lines) {
+			problems = append(problems, fmt.Sprintf("line %d: expected %q, got nothing", expected.line, expected.text))
+		} else if lines[i] != expected.text {
+			problems = append(problems, fmt.Sprintf("line %d: expected %q, got %q", expected.line, expected.text, lines[i]))
+		}
+	}
+
+	for _, line := range lines[min(len(output), len(lines)):] {
+		problems = append(problems, fmt.Sprintf("unexpected output %q", line))
+	}
+
+	failed := false
+	for _, err := range errs {
+		d := diagnostics.From(err)
+		if d.Code != diagnostics.CodeRuntime {
+			problems = append(problems, fmt.Sprintf("unexpected error: %s", err))
+			continue
+		}
+
+		failed = true
+		switch {
+		case runtimeError == nil:
+			problems = append(problems, fmt.Sprintf("unexpected runtime error: %s", err))
+		case runtimeError.text != d.Message || runtimeError.line != d.Token.Line:
+			problems = append(problems, fmt.Sprintf("line %d: expected runtime error %q, got %s",
+				runtimeError.line, runtimeError.text, err))
+		}
+	}
+
+	if runtimeError != nil && !failed {
+		problems = append(problems, fmt.Sprintf("line %d: expected runtime error %q", runtimeError.line, runtimeError.text))
+	}
+
+	return problems, nil
+}
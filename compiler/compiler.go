@@ -0,0 +1,912 @@
+package compiler
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/havrydotdev/golox/token"
+)
+
+const (
+	maxLocals   = math.MaxUint8 + 1
+	maxUpvalues = math.MaxUint8 + 1
+)
+
+type functionKind int
+
+const (
+	script functionKind = iota
+	function
+	method
+	initializer
+)
+
+type Error struct {
+	Token   token.Token
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("[line %d] Error at '%s': %s", e.Token.Line, e.Token.Lexeme, e.Message)
+}
+
+// Code is both the expression and the statement type of
+// the compiler algebra, emit writes its instructions into
+// the function which is being compiled when it is called
+type Code struct {
+	emit func()
+
+	// set for variables, classes use it
+	// to check that they don't inherit from themselves
+	variable *token.Token
+	// set for function declarations so
+	// that classes can compile them as methods
+	decl *funcDecl
+}
+
+type funcDecl struct {
+	name   token.Token
+	params []token.Token
+	body   []*Code
+}
+
+type local struct {
+	name     string
+	depth    int
+	captured bool
+}
+
+type upvalue struct {
+	index   uint8
+	isLocal bool
+}
+
+// funcState holds the state of the function which is being compiled
+type funcState struct {
+	enclosing *funcState
+	function  *Function
+	kind      functionKind
+
+	locals   []local
+	upvalues []upvalue
+	depth    int
+	loop     *loopState
+
+	// constant indexes of identifiers
+	names map[string]uint16
+}
+
+// loopState holds the jumps out of the loop which is being
+// compiled, they are patched once the loop is finished
+type loopState struct {
+	enclosing *loopState
+	// scope depth around the loop body
+	depth     int
+	breaks    []int
+	continues []int
+}
+
+type classState struct {
+	enclosing     *classState
+	hasSuperclass bool
+}
+
+type Compiler struct {
+	current *funcState
+	class   *classState
+	errors  []error
+
+	// last token seen, used for every emitted instruction
+	token token.Token
+}
+
+func New() *Compiler {
+	return &Compiler{}
+}
+
+// Compile emits the code of the parsed statements
+// into a function which represents the whole script
+func (c *Compiler) Compile(stmts []*Code) (*Function, []error) {
+	c.errors = nil
+	c.current = newFuncState(nil, "", script)
+
+	for _, stmt := range stmts {
+		stmt.run()
+	}
+	c.emitReturn()
+
+	return c.current.function, c.errors
+}
+
+// CompileExpression compiles a script
+// which returns the value of the expression
+func (c *Compiler) CompileExpression(expr *Code) (*Function, []error) {
+	c.errors = nil
+	c.current = newFuncState(nil, "", script)
+
+	expr.run()
+	c.emitOp(OpReturn)
+
+	return c.current.function, c.errors
+}
+
+func newFuncState(enclosing *funcState, name string, kind functionKind) *funcState {
+	fs := &funcState{
+		enclosing: enclosing,
+		function:  &Function{Name: name},
+		kind:      kind,
+		names:     make(map[string]uint16),
+	}
+
+	// slot zero holds the callee, or "this" inside of methods
+	slot := ""
+	if kind == method || kind == initializer {
+		slot = "this"
+	}
+	fs.locals = append(fs.locals, local{name: slot})
+
+	return fs
+}
+
+func (code *Code) run() {
+	if code != nil {
+		code.emit()
+	}
+}
+
+func (c *Compiler) Grouping(expr *Code) *Code {
+	return &Code{emit: expr.run}
+}
+
+func (c *Compiler) Literal(value any) *Code {
+	return &Code{emit: func() {
+		switch value {
+		case nil:
+			c.emitOp(OpNil)
+		case true:
+			c.emitOp(OpTrue)
+		case false:
+			c.emitOp(OpFalse)
+		default:
+			c.emitConstant(value)
+		}
+	}}
+}
+
+func (c *Compiler) This(keyword token.Token) *Code {
+	return &Code{emit: func() {
+		c.token = keyword
+		if c.class == nil {
+			c.error(keyword, "can't use 'this' outside of a class.")
+			return
+		}
+
+		c.namedVariable(keyword, nil)
+	}}
+}
+
+func (c *Compiler) Super(keyword token.Token, method token.Token) *Code {
+	return &Code{emit: func() {
+		c.token = keyword
+		if c.class == nil {
+			c.error(keyword, "can't use 'super' outside of a class.")
+			return
+		} else if !c.class.hasSuperclass {
+			c.error(keyword, "can't use 'super' in a class with no superclass.")
+			return
+		}
+
+		this := keyword
+		this.Kind, this.Lexeme = token.This, "this"
+		c.namedVariable(this, nil)
+		c.namedVariable(keyword, nil)
+		c.emitOp(OpGetSuper)
+		c.emitShort(c.identifier(method.Lexeme))
+	}}
+}
+
+func (c *Compiler) Variable(name token.Token) *Code {
+	return &Code{variable: &name, emit: func() {
+		c.token = name
+		c.namedVariable(name, nil)
+	}}
+}
+
+func (c *Compiler) Get(name token.Token, expr *Code) *Code {
+	return &Code{emit: func() {
+		expr.run()
+		c.token = name
+		c.emitOp(OpGetProperty)
+		c.emitShort(c.identifier(name.Lexeme))
+	}}
+}
+
+func (c *Compiler) Unary(op token.Token, right *Code) *Code {
+	return &Code{emit: func() {
+		right.run()
+		c.token = op
+
+		switch op.Kind {
+		case token.Minus:
+			c.emitOp(OpNegate)
+		case token.Bang:
+			c.emitOp(OpNot)
+		default:
+			c.error(op, "unexpected unary operator.")
+		}
+	}}
+}
+
+func (c *Compiler) Assign(name token.Token, value *Code) *Code {
+	return &Code{emit: func() {
+		c.token = name
+		c.namedVariable(name, value)
+	}}
+}
+
+func (c *Compiler) Binary(op token.Token, left, right *Code) *Code {
+	return &Code{emit: func() {
+		left.run()
+		right.run()
+		c.token = op
+
+		switch op.Kind {
+		case token.Plus:
+			c.emitOp(OpAdd)
+		case token.Minus:
+			c.emitOp(OpSubtract)
+		case token.Star:
+			c.emitOp(OpMultiply)
+		case token.Slash:
+			c.emitOp(OpDivide)
+		case token.EqualEqual:
+			c.emitOp(OpEqual)
+		case token.BangEqual:
+			c.emitOp(OpEqual)
+			c.emitOp(OpNot)
+		case token.Greater:
+			c.emitOp(OpGreater)
+		case token.GreaterEqual:
+			c.emitOp(OpGreaterEqual)
+		case token.Less:
+			c.emitOp(OpLess)
+		case token.LessEqual:
+			c.emitOp(OpLessEqual)
+		default:
+			c.error(op, "unexpected binary operator.")
+		}
+	}}
+}
+
+func (c *Compiler) Logical(op token.Token, left, right *Code) *Code {
+	return &Code{emit: func() {
+		left.run()
+		c.token = op
+
+		if op.Kind == token.Or {
+			elseJump := c.emitJump(OpJumpIfFalse)
+			endJump := c.emitJump(OpJump)
+
+			c.patchJump(elseJump)
+			c.emitOp(OpPop)
+			right.run()
+			c.patchJump(endJump)
+		} else {
+			endJump := c.emitJump(OpJumpIfFalse)
+			c.emitOp(OpPop)
+			right.run()
+			c.patchJump(endJump)
+		}
+	}}
+}
+
+func (c *Compiler) Call(callee *Code, paren token.Token, args []*Code) *Code {
+	return &Code{emit: func() {
+		callee.run()
+		for _, arg := range args {
+			arg.run()
+		}
+
+		c.token = paren
+		c.emitOp(OpCall)
+		c.emitByte(byte(len(args)))
+	}}
+}
+
+func (c *Compiler) Set(object *Code, name token.Token, value *Code) *Code {
+	return &Code{emit: func() {
+		object.run()
+		value.run()
+		c.token = name
+		c.emitOp(OpSetProperty)
+		c.emitShort(c.identifier(name.Lexeme))
+	}}
+}
+
+func (c *Compiler) List(bracket token.Token, elements []*Code) *Code {
+	return &Code{emit: func() {
+		for _, element := range elements {
+			element.run()
+		}
+
+		c.token = bracket
+		if len(elements) > math.MaxUint16 {
+			c.error(bracket, "too many elements in list literal.")
+			return
+		}
+
+		c.emitOp(OpList)
+		c.emitShort(uint16(len(elements)))
+	}}
+}
+
+func (c *Compiler) Map(brace token.Token, keys []*Code, values []*Code) *Code {
+	return &Code{emit: func() {
+		for i := range keys {
+			keys[i].run()
+			values[i].run()
+		}
+
+		c.token = brace
+		if len(keys) > math.MaxUint16 {
+			c.error(brace, "too many entries in map literal.")
+			return
+		}
+
+		c.emitOp(OpMap)
+		c.emitShort(uint16(len(keys)))
+	}}
+}
+
+func (c *Compiler) Index(object *Code, bracket token.Token, index *Code) *Code {
+	return &Code{emit: func() {
+		object.run()
+		index.run()
+		c.token = bracket
+		c.emitOp(OpGetIndex)
+	}}
+}
+
+func (c *Compiler) SetIndex(object *Code, bracket token.Token, index *Code, value *Code) *Code {
+	return &Code{emit: func() {
+		object.run()
+		index.run()
+		value.run()
+		c.token = bracket
+		c.emitOp(OpSetIndex)
+	}}
+}
+
+func (c *Compiler) Block(stmts []*Code) *Code {
+	return &Code{emit: func() {
+		c.beginScope()
+		for _, stmt := range stmts {
+			stmt.run()
+		}
+		c.endScope()
+	}}
+}
+
+func (c *Compiler) While(cond *Code, body *Code) *Code {
+	return &Code{emit: func() {
+		loopStart := len(c.chunk().Code)
+		cond.run()
+
+		exitJump := c.emitJump(OpJumpIfFalse)
+		c.emitOp(OpPop)
+		loop := c.loopBody(body)
+
+		c.patchJumps(loop.continues)
+		c.emitLoop(loopStart)
+
+		c.patchJump(exitJump)
+		c.emitOp(OpPop)
+		c.patchJumps(loop.breaks)
+	}}
+}
+
+func (c *Compiler) For(init *Code, cond *Code, incr *Code, body *Code) *Code {
+	return &Code{emit: func() {
+		c.beginScope()
+		init.run()
+
+		loopStart := len(c.chunk().Code)
+		exitJump := -1
+		if cond != nil {
+			cond.run()
+			exitJump = c.emitJump(OpJumpIfFalse)
+			c.emitOp(OpPop)
+		}
+
+		loop := c.loopBody(body)
+
+		// continue still runs the increment
+		c.patchJumps(loop.continues)
+		if incr != nil {
+			incr.run()
+			c.emitOp(OpPop)
+		}
+		c.emitLoop(loopStart)
+
+		if exitJump != -1 {
+			c.patchJump(exitJump)
+			c.emitOp(OpPop)
+		}
+		c.patchJumps(loop.breaks)
+
+		c.endScope()
+	}}
+}
+
+func (c *Compiler) Break(keyword token.Token) *Code {
+	return &Code{emit: func() {
+		c.token = keyword
+		if loop := c.jumpOutOfLoop(keyword); loop != nil {
+			loop.breaks = append(loop.breaks, c.emitJump(OpJump))
+		}
+	}}
+}
+
+func (c *Compiler) Continue(keyword token.Token) *Code {
+	return &Code{emit: func() {
+		c.token = keyword
+		if loop := c.jumpOutOfLoop(keyword); loop != nil {
+			loop.continues = append(loop.continues, c.emitJump(OpJump))
+		}
+	}}
+}
+
+func (c *Compiler) ExprStatement(expr *Code) *Code {
+	return &Code{emit: func() {
+		expr.run()
+		c.emitOp(OpPop)
+	}}
+}
+
+func (c *Compiler) If(cond *Code, then *Code, _else *Code) *Code {
+	return &Code{emit: func() {
+		cond.run()
+
+		thenJump := c.emitJump(OpJumpIfFalse)
+		c.emitOp(OpPop)
+		then.run()
+		elseJump := c.emitJump(OpJump)
+
+		c.patchJump(thenJump)
+		c.emitOp(OpPop)
+		_else.run()
+		c.patchJump(elseJump)
+	}}
+}
+
+func (c *Compiler) Var(name token.Token, init *Code) *Code {
+	return &Code{emit: func() {
+		c.token = name
+		c.declareVariable(name)
+
+		if init != nil {
+			init.run()
+		} else {
+			c.emitOp(OpNil)
+		}
+
+		c.defineVariable(name)
+	}}
+}
+
+func (c *Compiler) Return(keyword token.Token, value *Code) *Code {
+	return &Code{emit: func() {
+		c.token = keyword
+		if c.current.kind == script {
+			c.error(keyword, "can't return from top-level code.")
+			return
+		}
+
+		if value == nil {
+			c.emitReturn()
+			return
+		}
+
+		if c.current.kind == initializer {
+			c.error(keyword, "can't return a value from an initializer.")
+			return
+		}
+
+		value.run()
+		c.emitOp(OpReturn)
+	}}
+}
+
+func (c *Compiler) Class(name token.Token, superclass *Code, methods []*Code) *Code {
+	return &Code{emit: func() {
+		c.token = name
+		c.declareVariable(name)
+
+		c.emitOp(OpClass)
+		c.emitShort(c.identifier(name.Lexeme))
+		c.defineVariable(name)
+
+		c.class = &classState{enclosing: c.class}
+		defer func() { c.class = c.class.enclosing }()
+
+		if superclass != nil {
+			if superclass.variable != nil && superclass.variable.Lexeme == name.Lexeme {
+				c.error(name, "a class can't inherit from itself.")
+				return
+			}
+
+			superclass.run()
+
+			// methods capture "super" as a local
+			// of the scope around the class body
+			c.beginScope()
+			c.addLocal("super")
+			c.markInitialized()
+			defer c.endScope()
+
+			c.namedVariable(name, nil)
+			c.emitOp(OpInherit)
+			c.class.hasSuperclass = true
+		}
+
+		// class stays on the stack while its methods are bound
+		c.namedVariable(name, nil)
+		for _, m := range methods {
+			if m == nil || m.decl == nil {
+				continue
+			}
+
+			kind := method
+			if m.decl.name.Lexeme == "init" {
+				kind = initializer
+			}
+
+			c.function(m.decl, kind)
+			c.emitOp(OpMethod)
+			c.emitShort(c.identifier(m.decl.name.Lexeme))
+		}
+		c.emitOp(OpPop)
+	}}
+}
+
+func (c *Compiler) Function(name token.Token, params []token.Token, body []*Code) *Code {
+	decl := &funcDecl{name, params, body}
+
+	return &Code{decl: decl, emit: func() {
+		c.token = name
+		c.declareVariable(name)
+		// functions can refer to themselves
+		c.markInitialized()
+		c.function(decl, function)
+		c.defineVariable(name)
+	}}
+}
+
+func (c *Compiler) Lambda(keyword token.Token, params []token.Token, body []*Code) *Code {
+	// anonymous functions print as <fn lambda>
+	name := keyword
+	name.Kind, name.Lexeme = token.Identifier, "lambda"
+	decl := &funcDecl{name, params, body}
+
+	return &Code{emit: func() {
+		c.token = keyword
+		c.function(decl, function)
+	}}
+}
+
+func (c *Compiler) NilExpr() *Code {
+	return &Code{emit: func() {
+		c.error(token.NilV, "internal error: expression is missing.")
+	}}
+}
+
+func (c *Compiler) NilStmt() *Code {
+	return &Code{emit: func() {
+		c.error(token.NilV, "internal error: statement is missing.")
+	}}
+}
+
+func (c *Compiler) loopBody(body *Code) *loopState {
+	loop := &loopState{enclosing: c.current.loop, depth: c.current.depth}
+
+	c.current.loop = loop
+	body.run()
+	c.current.loop = loop.enclosing
+
+	return loop
+}
+
+// jumpOutOfLoop discards the locals of the loop body
+// before break or continue jumps out of it
+func (c *Compiler) jumpOutOfLoop(keyword token.Token) *loopState {
+	loop := c.current.loop
+	if loop == nil {
+		c.error(keyword, fmt.Sprintf("can't use '%s' outside of a loop.", keyword.Lexeme))
+		return nil
+	}
+
+	for i := len(c.current.locals) - 1; i >= 0 && c.current.locals[i].depth > loop.depth; i-- {
+		if c.current.locals[i].captured {
+			c.emitOp(OpCloseUpvalue)
+		} else {
+			c.emitOp(OpPop)
+		}
+	}
+
+	return loop
+}
+
+// function compiles the declaration into a new function
+// and emits the closure of it into the enclosing one
+func (c *Compiler) function(decl *funcDecl, kind functionKind) {
+	c.current = newFuncState(c.current, decl.name.Lexeme, kind)
+	c.beginScope()
+
+	for _, param := range decl.params {
+		c.current.function.Arity++
+		c.declareVariable(param)
+		c.defineVariable(param)
+	}
+
+	for _, stmt := range decl.body {
+		stmt.run()
+	}
+	c.emitReturn()
+
+	fs := c.current
+	c.current = fs.enclosing
+	fs.function.UpvalueCount = len(fs.upvalues)
+
+	c.emitOp(OpClosure)
+	c.emitShort(c.makeConstant(fs.function))
+	for _, up := range fs.upvalues {
+		if up.isLocal {
+			c.emitByte(1)
+		} else {
+			c.emitByte(0)
+		}
+
+		c.emitByte(up.index)
+	}
+}
+
+// namedVariable loads the variable, or stores value
+// into it if value is not nil
+func (c *Compiler) namedVariable(name token.Token, value *Code) {
+	var getOp, setOp OpCode
+	var arg uint16
+	wide := false
+
+	if slot := c.resolveLocal(c.current, name); slot != -1 {
+		getOp, setOp, arg = OpGetLocal, OpSetLocal, uint16(slot)
+	} else if slot := c.resolveUpvalue(c.current, name); slot != -1 {
+		getOp, setOp, arg = OpGetUpvalue, OpSetUpvalue, uint16(slot)
+	} else {
+		getOp, setOp, arg = OpGetGlobal, OpSetGlobal, c.identifier(name.Lexeme)
+		wide = true
+	}
+
+	op := getOp
+	if value != nil {
+		value.run()
+		op = setOp
+	}
+
+	c.emitOp(op)
+	if wide {
+		c.emitShort(arg)
+	} else {
+		c.emitByte(byte(arg))
+	}
+}
+
+func (c *Compiler) resolveLocal(fs *funcState, name token.Token) int {
+	for i := len(fs.locals) - 1; i >= 0; i-- {
+		if fs.locals[i].name == name.Lexeme {
+			if fs.locals[i].depth == -1 {
+				c.error(name, "can't read local variable in its own initializer.")
+			}
+
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (c *Compiler) resolveUpvalue(fs *funcState, name token.Token) int {
+	if fs.enclosing == nil {
+		return -1
+	}
+
+	if slot := c.resolveLocal(fs.enclosing, name); slot != -1 {
+		fs.enclosing.locals[slot].captured = true
+		return c.addUpvalue(fs, name, uint8(slot), true)
+	}
+
+	if slot := c.resolveUpvalue(fs.enclosing, name); slot != -1 {
+		return c.addUpvalue(fs, name, uint8(slot), false)
+	}
+
+	return -1
+}
+
+func (c *Compiler) addUpvalue(fs *funcState, name token.Token, index uint8, isLocal bool) int {
+	for i, up := range fs.upvalues {
+		if up.index == index && up.isLocal == isLocal {
+			return i
+		}
+	}
+
+	if len(fs.upvalues) == maxUpvalues {
+		c.error(name, "too many closure variables in function.")
+		return 0
+	}
+
+	fs.upvalues = append(fs.upvalues, upvalue{index, isLocal})
+	return len(fs.upvalues) - 1
+}
+
+// declareVariable adds a local, globals are late bound
+// and don't need to be declared
+func (c *Compiler) declareVariable(name token.Token) {
+	if c.current.depth == 0 {
+		return
+	}
+
+	for i := len(c.current.locals) - 1; i >= 0; i-- {
+		l := c.current.locals[i]
+		if l.depth != -1 && l.depth < c.current.depth {
+			break
+		}
+
+		if l.name == name.Lexeme {
+			c.error(name, "already a variable with this name in this scope.")
+		}
+	}
+
+	if len(c.current.locals) == maxLocals {
+		c.error(name, "too many local variables in function.")
+		return
+	}
+
+	c.addLocal(name.Lexeme)
+}
+
+func (c *Compiler) addLocal(name string) {
+	c.current.locals = append(c.current.locals, local{name: name, depth: -1})
+}
+
+func (c *Compiler) defineVariable(name token.Token) {
+	if c.current.depth > 0 {
+		c.markInitialized()
+		return
+	}
+
+	c.emitOp(OpDefineGlobal)
+	c.emitShort(c.identifier(name.Lexeme))
+}
+
+func (c *Compiler) markInitialized() {
+	if c.current.depth == 0 {
+		return
+	}
+
+	c.current.locals[len(c.current.locals)-1].depth = c.current.depth
+}
+
+func (c *Compiler) beginScope() {
+	c.current.depth++
+}
+
+func (c *Compiler) endScope() {
+	c.current.depth--
+
+	locals := c.current.locals
+	for len(locals) > 0 && locals[len(locals)-1].depth > c.current.depth {
+		if locals[len(locals)-1].captured {
+			c.emitOp(OpCloseUpvalue)
+		} else {
+			c.emitOp(OpPop)
+		}
+
+		locals = locals[:len(locals)-1]
+	}
+	c.current.locals = locals
+}
+
+func (c *Compiler) chunk() *Chunk {
+	return &c.current.function.Chunk
+}
+
+func (c *Compiler) identifier(name string) uint16 {
+	if idx, ok := c.current.names[name]; ok {
+		return idx
+	}
+
+	idx := c.makeConstant(name)
+	c.current.names[name] = idx
+
+	return idx
+}
+
+func (c *Compiler) makeConstant(value any) uint16 {
+	idx := c.chunk().addConstant(value)
+	if idx > math.MaxUint16 {
+		c.error(token.NilV, "too many constants in one chunk.")
+		return 0
+	}
+
+	return uint16(idx)
+}
+
+func (c *Compiler) emitConstant(value any) {
+	c.emitOp(OpConstant)
+	c.emitShort(c.makeConstant(value))
+}
+
+func (c *Compiler) emitReturn() {
+	if c.current.kind == initializer {
+		c.emitOp(OpGetLocal)
+		c.emitByte(0)
+	} else {
+		c.emitOp(OpNil)
+	}
+
+	c.emitOp(OpReturn)
+}
+
+// emitJump emits a jump with a placeholder offset
+// and returns the position of the offset
+func (c *Compiler) emitJump(op OpCode) int {
+	c.emitOp(op)
+	c.emitShort(math.MaxUint16)
+
+	return len(c.chunk().Code) - 2
+}
+
+func (c *Compiler) patchJump(offset int) {
+	code := c.chunk().Code
+
+	jump := len(code) - offset - 2
+	if jump > math.MaxUint16 {
+		c.error(token.NilV, "too much code to jump over.")
+	}
+
+	code[offset] = byte(jump >> 8)
+	code[offset+1] = byte(jump)
+}
+
+func (c *Compiler) patchJumps(offsets []int) {
+	for _, offset := range offsets {
+		c.patchJump(offset)
+	}
+}
+
+func (c *Compiler) emitLoop(loopStart int) {
+	c.emitOp(OpLoop)
+
+	offset := len(c.chunk().Code) - loopStart + 2
+	if offset > math.MaxUint16 {
+		c.error(token.NilV, "loop body too large.")
+	}
+
+	c.emitShort(uint16(offset))
+}
+
+func (c *Compiler) emitOp(op OpCode) {
+	c.emitByte(byte(op))
+}
+
+func (c *Compiler) emitShort(value uint16) {
+	c.emitByte(byte(value >> 8))
+	c.emitByte(byte(value))
+}
+
+func (c *Compiler) emitByte(b byte) {
+	c.chunk().write(b, c.token)
+}
+
+func (c *Compiler) error(tok token.Token, message string) {
+	c.errors = append(c.errors, Error{tok, message})
+}
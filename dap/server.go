@@ -0,0 +1,535 @@
+// Package dap is a debugger for lox programs, it speaks the
+// Debug Adapter Protocol over a stream and runs the program on
+// the tree-walking evaluator, stopping it with its statement hook
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/havrydotdev/golox/diagnostics"
+	env "github.com/havrydotdev/golox/environment"
+	eval "github.com/havrydotdev/golox/evaluator"
+	"github.com/havrydotdev/golox/lox"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/resolver"
+	"github.com/havrydotdev/golox/scanner"
+	"github.com/havrydotdev/golox/token"
+)
+
+// lox programs only ever have one thread
+const threadID = 1
+
+// errDisconnected unwinds the program when the client is gone
+var errDisconnected = errors.New("the debugger disconnected")
+
+type stepKind int
+
+const (
+	noStep stepKind = iota
+	stepIn
+	stepOver
+	stepOut
+)
+
+// Server debugs one program for one client, the requests are handled
+// by Serve while the program runs on its own goroutine
+type Server struct {
+	in *bufio.Reader
+
+	// guards the writes, events are sent by both goroutines
+	writeMu sync.Mutex
+	out     io.Writer
+	seq     int
+	closed  bool
+
+	// guards the state shared with the program
+	mu          sync.Mutex
+	evaluator   *eval.Evaluator
+	program     []eval.StmtEvaluator
+	source      string
+	launched    bool
+	configured  bool
+	started     bool
+	stopOnEntry bool
+	breakpoints map[string]map[int]bool
+	step        stepKind
+	// depth of the calls when the step was requested
+	stepDepth int
+	pause     bool
+	// the statement the hook ran for last
+	lastLine, lastDepth int
+
+	stopped    bool
+	terminated bool
+	frames     []eval.Frame
+	// values of the variable references, a reference is the
+	// index plus one, they are only valid while stopped
+	refs []any
+
+	resume chan bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:          bufio.NewReader(in),
+		out:         out,
+		breakpoints: map[string]map[int]bool{},
+		resume:      make(chan bool),
+	}
+}
+
+type handler func(s *Server, req message) error
+
+var handlers = map[string]handler{
+	"initialize":        (*Server).initialize,
+	"launch":            (*Server).launch,
+	"setBreakpoints":    (*Server).setBreakpoints,
+	"configurationDone": (*Server).configurationDone,
+	"threads":           (*Server).threads,
+	"stackTrace":        (*Server).stackTrace,
+	"scopes":            (*Server).scopes,
+	"variables":         (*Server).variables,
+	"continue":          resumeWith(noStep),
+	"next":              resumeWith(stepOver),
+	"stepIn":            resumeWith(stepIn),
+	"stepOut":           resumeWith(stepOut),
+	"pause":             (*Server).pauseRequest,
+	"terminate":         (*Server).terminate,
+}
+
+// Serve handles requests until the client disconnects,
+// it fails if the stream breaks before that
+func (s *Server) Serve() error {
+	for {
+		body, err := readMessage(s.in)
+		if errors.Is(err, io.EOF) {
+			s.stop()
+			return errors.New("the client closed the stream without disconnecting")
+		} else if err != nil {
+			s.stop()
+			return err
+		}
+
+		var req message
+		if err := json.Unmarshal(body, &req); err != nil || req.Type != "request" {
+			continue
+		}
+
+		if req.Command == "disconnect" {
+			s.stop()
+			err := s.respond(req, nil)
+
+			s.writeMu.Lock()
+			s.closed = true
+			s.writeMu.Unlock()
+			return err
+		}
+
+		handle, ok := handlers[req.Command]
+		if !ok {
+			err = fmt.Errorf("unsupported request %s", req.Command)
+		} else {
+			err = handle(s, req)
+		}
+
+		if err != nil {
+			if err := s.fail(req, err); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// output is a writer whose writes are sent to
+// the client as output of the program
+func (s *Server) output() io.Writer {
+	return outputWriter{s}
+}
+
+type outputWriter struct {
+	s *Server
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	w.s.event("output", map[string]any{"category": "stdout", "output": string(p)})
+	return len(p), nil
+}
+
+func (s *Server) send(msg message, body any) error {
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		msg.Body = raw
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.closed {
+		return nil
+	}
+
+	s.seq++
+	msg.Seq = s.seq
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(raw), raw)
+	return err
+}
+
+func (s *Server) respond(req message, body any) error {
+	success := true
+	return s.send(message{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: &success}, body)
+}
+
+func (s *Server) fail(req message, err error) error {
+	success := false
+	return s.send(message{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Command:    req.Command,
+		Success:    &success,
+		Message:    err.Error(),
+	}, nil)
+}
+
+// event can't fail the program, a client which
+// can't be written to is noticed by Serve
+func (s *Server) event(name string, body any) {
+	s.send(message{Type: "event", Event: name}, body)
+}
+
+func decode[T any](req message) (T, error) {
+	var v T
+	if len(req.Arguments) == 0 {
+		return v, nil
+	}
+
+	err := json.Unmarshal(req.Arguments, &v)
+	return v, err
+}
+
+func (s *Server) initialize(req message) error {
+	err := s.respond(req, map[string]any{
+		"supportsConfigurationDoneRequest": true,
+		"supportsTerminateRequest":         true,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.event("initialized", nil)
+	return nil
+}
+
+// launch loads the program, it only starts
+// once the configuration is done as well
+func (s *Server) launch(req message) error {
+	args, err := decode[launchArguments](req)
+	if err != nil {
+		return err
+	}
+
+	text, err := os.ReadFile(args.Program)
+	if err != nil {
+		return err
+	}
+
+	source := string(text)
+	path := filepath.Clean(args.Program)
+	e := eval.New().(*eval.Evaluator)
+	e.SetHook(s.hook)
+	e.SetStdout(s.output())
+
+	program, errs := load(e, path, source)
+	if len(errs) != 0 {
+		var out strings.Builder
+		renderer := diagnostics.NewRenderer(&out, source)
+		for _, err := range errs {
+			renderer.Render(diagnostics.From(err))
+		}
+
+		return errors.New(out.String())
+	}
+
+	s.mu.Lock()
+	s.evaluator, s.program, s.source, s.stopOnEntry = e, program, source, args.StopOnEntry
+	s.launched = true
+	s.mu.Unlock()
+
+	if err := s.respond(req, nil); err != nil {
+		return err
+	}
+
+	s.start()
+	return nil
+}
+
+func load(e *eval.Evaluator, path, source string) ([]eval.StmtEvaluator, []error) {
+	tokens, errs := scanner.NewFile(path, source).Scan()
+	if len(errs) != 0 {
+		return nil, errs
+	}
+
+	res := resolver.New[eval.ExpEvaluator, eval.StmtEvaluator](e)
+	nodes, errs := parser.New(tokens, res).Parse()
+	if len(errs) != 0 {
+		return nil, errs
+	}
+
+	return res.Resolve(nodes)
+}
+
+func (s *Server) configurationDone(req message) error {
+	s.mu.Lock()
+	s.configured = true
+	s.mu.Unlock()
+
+	if err := s.respond(req, nil); err != nil {
+		return err
+	}
+
+	s.start()
+	return nil
+}
+
+// start runs the program once it is
+// both launched and configured
+func (s *Server) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || !s.configured || !s.launched {
+		return
+	}
+
+	s.started = true
+	go s.run(s.program, s.source)
+}
+
+func (s *Server) run(program []eval.StmtEvaluator, source string) {
+	code := 0
+	for _, stmt := range program {
+		err := stmt.Eval()
+		if err == nil {
+			continue
+		}
+
+		var interrupt *eval.Interrupt
+		if !errors.As(err, &interrupt) {
+			var out strings.Builder
+			diagnostics.NewRenderer(&out, source).Render(diagnostics.From(err))
+			s.event("output", map[string]any{"category": "stderr", "output": out.String()})
+			code = 70
+		}
+
+		break
+	}
+
+	s.event("exited", map[string]any{"exitCode": code})
+	s.event("terminated", nil)
+}
+
+func (s *Server) setBreakpoints(req message) error {
+	args, err := decode[setBreakpointsArguments](req)
+	if err != nil {
+		return err
+	}
+
+	lines := map[int]bool{}
+	breakpoints := []breakpoint{}
+	for _, bp := range args.Breakpoints {
+		lines[bp.Line] = true
+		breakpoints = append(breakpoints, breakpoint{Verified: true, Line: bp.Line})
+	}
+
+	s.mu.Lock()
+	s.breakpoints[filepath.Clean(args.Source.Path)] = lines
+	s.mu.Unlock()
+
+	return s.respond(req, map[string]any{"breakpoints": breakpoints})
+}
+
+func (s *Server) threads(req message) error {
+	return s.respond(req, map[string]any{
+		"threads": []map[string]any{{"id": threadID, "name": "main"}},
+	})
+}
+
+func (s *Server) stackTrace(req message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.stopped {
+		return errors.New("the program is not stopped")
+	}
+
+	frames := make([]stackFrame, len(s.frames))
+	for i, frame := range s.frames {
+		frames[i] = stackFrame{
+			ID:     i + 1,
+			Name:   frame.Function,
+			Source: source{Name: filepath.Base(frame.Token.File), Path: frame.Token.File},
+			Line:   frame.Token.Line,
+			Column: frame.Token.Column,
+		}
+	}
+
+	return s.respond(req, map[string]any{"stackFrames": frames, "totalFrames": len(frames)})
+}
+
+// scopes are the environments around the frame's code, from
+// the innermost one to the globals, the empty ones are left out
+func (s *Server) scopes(req message) error {
+	args, err := decode[struct {
+		FrameID int `json:"frameId"`
+	}](req)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.stopped || args.FrameID < 1 || args.FrameID > len(s.frames) {
+		return fmt.Errorf("unknown frame %d", args.FrameID)
+	}
+
+	globals := s.evaluator.Globals()
+	scopes := []scope{}
+	for environment := s.frames[args.FrameID-1].Env; environment != nil; environment = environment.Outer() {
+		switch {
+		case environment == globals:
+			scopes = append(scopes, scope{Name: "Globals", VariablesReference: s.reference(environment)})
+		case len(environment.Names()) == 0:
+		case len(scopes) == 0:
+			scopes = append(scopes, scope{Name: "Locals", VariablesReference: s.reference(environment)})
+		default:
+			scopes = append(scopes, scope{Name: "Enclosing", VariablesReference: s.reference(environment)})
+		}
+	}
+
+	return s.respond(req, map[string]any{"scopes": scopes})
+}
+
+// resumeWith handles the requests which continue the program
+func resumeWith(step stepKind) handler {
+	return func(s *Server, req message) error {
+		s.mu.Lock()
+		if !s.stopped {
+			s.mu.Unlock()
+			return errors.New("the program is not stopped")
+		}
+
+		s.step, s.stepDepth = step, len(s.frames)-1
+		s.stopped, s.frames, s.refs = false, nil, nil
+		s.mu.Unlock()
+
+		// the response has to come before the next stop
+		if err := s.respond(req, map[string]any{"allThreadsContinued": true}); err != nil {
+			return err
+		}
+
+		s.resume <- true
+		return nil
+	}
+}
+
+func (s *Server) pauseRequest(req message) error {
+	s.mu.Lock()
+	s.pause = !s.stopped
+	s.mu.Unlock()
+
+	return s.respond(req, nil)
+}
+
+func (s *Server) terminate(req message) error {
+	s.stop()
+	return s.respond(req, nil)
+}
+
+// stop unwinds the program at its next statement
+func (s *Server) stop() {
+	s.mu.Lock()
+	stopped := s.stopped
+	s.terminated, s.stopped = true, false
+	s.mu.Unlock()
+
+	if stopped {
+		s.resume <- false
+	}
+}
+
+// hook runs on the program's goroutine before every
+// statement and blocks it while the program is stopped
+func (s *Server) hook(e *eval.Evaluator, start token.Token) error {
+	s.mu.Lock()
+	if s.terminated {
+		s.mu.Unlock()
+		return errDisconnected
+	}
+
+	line, depth := start.Line, e.Depth()
+	reason := s.reason(filepath.Clean(start.File), line, depth)
+	s.lastLine, s.lastDepth = line, depth
+	if reason == "" {
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.stopped, s.step, s.pause, s.stopOnEntry = true, noStep, false, false
+	s.frames, s.refs = e.Stack(start), nil
+	s.mu.Unlock()
+
+	s.event("stopped", map[string]any{"reason": reason, "threadId": threadID, "allThreadsStopped": true})
+	if !<-s.resume {
+		return errDisconnected
+	}
+
+	return nil
+}
+
+// reason tells why the program stops at the statement, statements
+// on the line the program stopped at last are stepped over,
+// otherwise it would stop at every statement of the line
+func (s *Server) reason(file string, line, depth int) string {
+	moved := line != s.lastLine || depth != s.lastDepth
+	switch {
+	case s.pause:
+		return "pause"
+	case s.stopOnEntry:
+		return "entry"
+	case !moved:
+		return ""
+	case s.step == stepIn,
+		s.step == stepOver && depth <= s.stepDepth,
+		s.step == stepOut && depth < s.stepDepth:
+		return "step"
+	case s.breakpoints[file][line]:
+		return "breakpoint"
+	}
+
+	return ""
+}
+
+// reference registers the value so that its children can be
+// requested, it is 0 for values which have no children
+func (s *Server) reference(value any) int {
+	switch value.(type) {
+	case *env.Env, *eval.Instance, *lox.List, *lox.Map:
+		s.refs = append(s.refs, value)
+		return len(s.refs)
+	}
+
+	return 0
+}
@@ -0,0 +1,333 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/havrydotdev/golox/lox"
+)
+
+// client drives a server running on its own goroutine,
+// the events received while waiting for a response are
+// kept until the test asks for them
+type client struct {
+	t        *testing.T
+	w        io.Writer
+	seq      int
+	messages chan message
+	events   []message
+	done     chan error
+}
+
+func connect(t *testing.T) *client {
+	t.Helper()
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{t: t, w: inW, messages: make(chan message, 64), done: make(chan error, 1)}
+
+	go func() {
+		c.done <- NewServer(inR, outW).Serve()
+		outW.Close()
+	}()
+
+	go func() {
+		r := bufio.NewReader(outR)
+		for {
+			body, err := readMessage(r)
+			if err != nil {
+				close(c.messages)
+				return
+			}
+
+			var msg message
+			json.Unmarshal(body, &msg)
+			c.messages <- msg
+		}
+	}()
+
+	return c
+}
+
+func (c *client) next() message {
+	c.t.Helper()
+
+	select {
+	case msg, ok := <-c.messages:
+		if !ok {
+			c.t.Fatal("the server closed the stream")
+		}
+
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for the server")
+	}
+
+	return message{}
+}
+
+// request sends the request and waits for its response
+func (c *client) request(command string, args any) message {
+	c.t.Helper()
+
+	c.seq++
+	raw, _ := json.Marshal(args)
+	body, _ := json.Marshal(message{Seq: c.seq, Type: "request", Command: command, Arguments: raw})
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		c.t.Fatal(err)
+	}
+
+	for {
+		msg := c.next()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+
+		if msg.RequestSeq != c.seq {
+			c.t.Fatalf("unexpected response %+v", msg)
+		}
+
+		return msg
+	}
+}
+
+// success sends the request and decodes the body of the response
+func success[T any](c *client, command string, args any) T {
+	c.t.Helper()
+
+	resp := c.request(command, args)
+	if resp.Success == nil || !*resp.Success {
+		c.t.Fatalf("%s failed: %s", command, resp.Message)
+	}
+
+	var body T
+	if resp.Body != nil {
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+
+	return body
+}
+
+// event waits for the event, skipping the other ones
+func (c *client) event(name string) map[string]any {
+	c.t.Helper()
+
+	for {
+		var msg message
+		if len(c.events) != 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.next()
+		}
+
+		if msg.Type == "event" && msg.Event == name {
+			var body map[string]any
+			json.Unmarshal(msg.Body, &body)
+			return body
+		}
+	}
+}
+
+func (c *client) stopped(reason string) {
+	c.t.Helper()
+
+	if body := c.event("stopped"); body["reason"] != reason {
+		c.t.Fatalf("expected to stop for %s, got %v", reason, body)
+	}
+}
+
+func (c *client) stack() []stackFrame {
+	c.t.Helper()
+
+	return success[struct {
+		StackFrames []stackFrame `json:"stackFrames"`
+	}](c, "stackTrace", map[string]any{"threadId": threadID}).StackFrames
+}
+
+func (c *client) expectStack(lines map[string]int) {
+	c.t.Helper()
+
+	frames := c.stack()
+	if len(frames) != len(lines) {
+		c.t.Fatalf("expected %d frames, got %+v", len(lines), frames)
+	}
+
+	for _, frame := range frames {
+		if lines[frame.Name] != frame.Line {
+			c.t.Errorf("expected %s at line %d, got %d", frame.Name, lines[frame.Name], frame.Line)
+		}
+	}
+}
+
+// variables are the values of the frame's first scope by name
+func (c *client) variables(frame int) map[string]string {
+	c.t.Helper()
+
+	scopes := success[struct {
+		Scopes []scope `json:"scopes"`
+	}](c, "scopes", map[string]any{"frameId": frame}).Scopes
+	if len(scopes) == 0 {
+		c.t.Fatal("expected scopes")
+	}
+
+	vars := success[struct {
+		Variables []variable `json:"variables"`
+	}](c, "variables", map[string]any{"variablesReference": scopes[0].VariablesReference}).Variables
+
+	values := map[string]string{}
+	for _, v := range vars {
+		values[v.Name] = v.Value
+	}
+
+	return values
+}
+
+func (c *client) disconnect() {
+	c.t.Helper()
+
+	success[any](c, "disconnect", nil)
+	select {
+	case err := <-c.done:
+		if err != nil {
+			c.t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("the server didn't stop")
+	}
+}
+
+func program(t *testing.T, source string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "main.lox")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func number(n float32) string {
+	return lox.Stringify(n)
+}
+
+func TestBreakpointsAndStepping(t *testing.T) {
+	path := program(t, `fun add(a, b) {
+  var sum = a + b;
+  return sum;
+}
+
+var x = 1;
+var y = add(x, 2);
+var z = y * 2;
+print(z);
+`)
+
+	c := connect(t)
+	success[any](c, "initialize", map[string]any{"adapterID": "golox"})
+	c.event("initialized")
+	success[any](c, "launch", map[string]any{"program": path})
+	success[any](c, "setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": path},
+		"breakpoints": []any{map[string]any{"line": 2}},
+	})
+	success[any](c, "configurationDone", nil)
+
+	c.stopped("breakpoint")
+	c.expectStack(map[string]int{"add": 2, "script": 7})
+	if vars := c.variables(1); vars["a"] != number(1) || vars["b"] != number(2) || len(vars) != 2 {
+		t.Errorf("unexpected locals %v", vars)
+	}
+
+	success[any](c, "next", map[string]any{"threadId": threadID})
+	c.stopped("step")
+	c.expectStack(map[string]int{"add": 3, "script": 7})
+	if vars := c.variables(1); vars["sum"] != number(3) {
+		t.Errorf("unexpected locals %v", vars)
+	}
+
+	success[any](c, "stepOut", map[string]any{"threadId": threadID})
+	c.stopped("step")
+	c.expectStack(map[string]int{"script": 8})
+	vars := c.variables(1)
+	if vars["y"] != number(3) || vars["add"] != "<fn add>" {
+		t.Errorf("unexpected globals %v", vars)
+	}
+
+	if _, ok := vars["print"]; ok {
+		t.Error("natives should be hidden")
+	}
+
+	success[any](c, "continue", map[string]any{"threadId": threadID})
+	if body := c.event("output"); body["output"] != number(6)+"\n" {
+		t.Errorf("expected the output of print, got %v", body)
+	}
+
+	if body := c.event("exited"); body["exitCode"] != float64(0) {
+		t.Errorf("unexpected exit %v", body)
+	}
+
+	c.event("terminated")
+	c.disconnect()
+}
+
+func TestEntryStepInAndPause(t *testing.T) {
+	path := program(t, `var i = 0;
+fun f() { return 1; }
+var one = f();
+while (true) {
+  i = i + 1;
+}
+`)
+
+	c := connect(t)
+	success[any](c, "initialize", nil)
+	success[any](c, "launch", map[string]any{"program": path, "stopOnEntry": true})
+	success[any](c, "configurationDone", nil)
+
+	c.stopped("entry")
+	c.expectStack(map[string]int{"script": 1})
+
+	for _, line := range []int{2, 3} {
+		success[any](c, "next", map[string]any{"threadId": threadID})
+		c.stopped("step")
+		c.expectStack(map[string]int{"script": line})
+	}
+
+	success[any](c, "stepIn", map[string]any{"threadId": threadID})
+	c.stopped("step")
+	c.expectStack(map[string]int{"f": 2, "script": 3})
+
+	success[any](c, "continue", map[string]any{"threadId": threadID})
+	success[any](c, "pause", map[string]any{"threadId": threadID})
+	c.stopped("pause")
+	// the pause can come before the loop starts
+	if frames := c.stack(); len(frames) != 1 || frames[0].Line < 4 {
+		t.Errorf("expected to pause in the loop, got %+v", frames)
+	}
+
+	c.disconnect()
+}
+
+func TestLaunchErrors(t *testing.T) {
+	path := program(t, "var a = ;")
+
+	c := connect(t)
+	success[any](c, "initialize", nil)
+	resp := c.request("launch", map[string]any{"program": path})
+	if resp.Success == nil || *resp.Success || !strings.Contains(resp.Message, "expected expression") {
+		t.Errorf("expected the launch to fail, got %+v", resp)
+	}
+
+	c.disconnect()
+}
@@ -0,0 +1,91 @@
+package dap
+
+import (
+	"fmt"
+
+	env "github.com/havrydotdev/golox/environment"
+	eval "github.com/havrydotdev/golox/evaluator"
+	"github.com/havrydotdev/golox/lox"
+)
+
+// variables are the children of a reference, the names
+// of an environment, the fields of an instance or the
+// elements of a list or a map
+func (s *Server) variables(req message) error {
+	args, err := decode[struct {
+		VariablesReference int `json:"variablesReference"`
+	}](req)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref := args.VariablesReference
+	if !s.stopped || ref < 1 || ref > len(s.refs) {
+		return fmt.Errorf("unknown variables reference %d", ref)
+	}
+
+	vars := []variable{}
+	add := func(name string, value any) {
+		vars = append(vars, variable{
+			Name:               name,
+			Value:              lox.Stringify(value),
+			Type:               typeName(value),
+			VariablesReference: s.reference(value),
+		})
+	}
+
+	switch value := s.refs[ref-1].(type) {
+	case *env.Env:
+		for _, name := range value.Names() {
+			val, _ := value.GetAt(0, name)
+			if _, native := val.(*eval.NativeFun); !native {
+				add(name, val)
+			}
+		}
+	case *eval.Instance:
+		for _, name := range value.Fields() {
+			val, _ := value.Get(name)
+			add(name, val)
+		}
+	case *lox.List:
+		length, _ := lox.Len(value)
+		for i := range int(length.(float32)) {
+			val, _ := value.Get(float32(i))
+			add(fmt.Sprintf("[%d]", i), val)
+		}
+	case *lox.Map:
+		values := value.Values()
+		for i, key := range value.Keys() {
+			add(fmt.Sprintf("[%s]", lox.Stringify(key)), values[i])
+		}
+	}
+
+	return s.respond(req, map[string]any{"variables": vars})
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "bool"
+	case float32:
+		return "number"
+	case string:
+		return "string"
+	case *lox.List:
+		return "list"
+	case *lox.Map:
+		return "map"
+	case *eval.Instance:
+		return "instance"
+	case *eval.Class:
+		return "class"
+	case eval.Callable:
+		return "function"
+	}
+
+	return ""
+}
@@ -0,0 +1,95 @@
+// Package diagnostics turns the errors of every stage
+// into diagnostics and renders them with the source
+package diagnostics
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/havrydotdev/golox/compiler"
+	eval "github.com/havrydotdev/golox/evaluator"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/resolver"
+	"github.com/havrydotdev/golox/scanner"
+	"github.com/havrydotdev/golox/token"
+	"github.com/havrydotdev/golox/vm"
+)
+
+// Severity only has Error, nothing reports warnings yet
+type Severity int
+
+const Error Severity = 0
+
+func (s Severity) String() string {
+	return "error"
+}
+
+// codes name the stage which reported the diagnostic
+const (
+	CodeRead    = "read"
+	CodeScan    = "scan"
+	CodeSyntax  = "syntax"
+	CodeResolve = "resolve"
+	CodeCompile = "compile"
+	CodeRuntime = "runtime"
+)
+
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	// Token is the span the diagnostic points at, its
+	// Column is 0 when only the line is known
+	Token   token.Token
+	Message string
+	// Hint is empty when there is nothing to suggest
+	Hint string
+	// Trace is the stack trace of runtime errors
+	Trace string
+}
+
+// From converts an error of the scanner, parser, resolver,
+// compiler or one of the backends into a diagnostic,
+// other errors only keep their message
+func From(err error) Diagnostic {
+	var (
+		scanErr    scanner.Error
+		parseErr   parser.Error
+		resolveErr resolver.Error
+		compileErr compiler.Error
+		evalErr    *eval.RuntimeError
+		vmErr      *vm.RuntimeError
+	)
+
+	switch {
+	case errors.As(err, &scanErr):
+		return Diagnostic{Code: CodeScan, Token: scanErr.Token, Message: scanErr.Message}
+	case errors.As(err, &parseErr):
+		return Diagnostic{Code: CodeSyntax, Token: parseErr.Token, Message: parseErr.Message, Hint: parseErr.Hint}
+	case errors.As(err, &resolveErr):
+		return Diagnostic{Code: CodeResolve, Token: resolveErr.Token, Message: resolveErr.Message}
+	case errors.As(err, &compileErr):
+		return Diagnostic{Code: CodeCompile, Token: compileErr.Token, Message: compileErr.Message}
+	case errors.As(err, &evalErr):
+		return Diagnostic{Code: CodeRuntime, Token: evalErr.Token, Message: evalErr.Message, Trace: evalErr.Trace()}
+	case errors.As(err, &vmErr):
+		return Diagnostic{Code: CodeRuntime, Token: vmErr.Token, Message: vmErr.Message, Trace: vmErr.Trace()}
+	}
+
+	return Diagnostic{Message: err.Error()}
+}
+
+// End is the position right after the span, lexemes
+// of strings can go over several lines
+func (d Diagnostic) End() (line int, column int) {
+	lexeme := d.Token.Lexeme
+	if d.Token.Column == 0 {
+		return d.Token.Line, 0
+	}
+
+	i := strings.LastIndexByte(lexeme, '\n')
+	if i == -1 {
+		return d.Token.Line, d.Token.Column + len(lexeme)
+	}
+
+	return d.Token.Line + strings.Count(lexeme, "\n"), len(lexeme) - i
+}
@@ -0,0 +1,83 @@
+package diagnostics
+
+import (
+	"bytes"
+	"testing"
+
+	eval "github.com/havrydotdev/golox/evaluator"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/scanner"
+)
+
+func TestRender(t *testing.T) {
+	source := "var a = 1;\n\t(a) = \"x\" @;\n"
+	tokens, errs := scanner.NewFile("main.lox", source).Scan()
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 scanner error, got %v", errs)
+	}
+
+	_, parseErrs := parser.New(tokens, eval.New()).Parse()
+	errs = append(errs, parseErrs...)
+
+	var out bytes.Buffer
+	renderer := NewRenderer(&out, source)
+	for _, err := range errs {
+		renderer.Render(From(err))
+	}
+
+	expected := `error: unexpected character '@'.
+ --> main.lox:2:12
+  |
+2 | 	(a) = "x" @;
+  | 	          ^
+error: invalid assignment target.
+ --> main.lox:2:6
+  |
+2 | 	(a) = "x" @;
+  | 	    ^
+  = hint: only variables, fields and list or map elements can be assigned to
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestUnderline(t *testing.T) {
+	tests := []struct {
+		line           string
+		column, length int
+		expected       string
+	}{
+		{"var a;", 5, 1, "    ^"},
+		{"\tx = \"ab", 6, 20, "\t    ^^^"},
+		{"end", 4, 0, "   ^"},
+		{"é = 1", 1, 2, "^"},
+	}
+
+	for _, test := range tests {
+		if actual := underline(test.line, test.column, test.length); actual != test.expected {
+			t.Errorf("underline(%q, %d, %d) = %q, expected %q", test.line, test.column, test.length, actual, test.expected)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	_, errs := scanner.NewFile("main.lox", "var s = \"a\nbc").Scan()
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+
+	expected := Record{
+		File:      "main.lox",
+		Line:      1,
+		Column:    9,
+		EndLine:   2,
+		EndColumn: 3,
+		Severity:  "error",
+		Code:      CodeScan,
+		Message:   "unterminated string.",
+	}
+	if record := From(errs[0]).Record(); record != expected {
+		t.Errorf("expected %+v, got %+v", expected, record)
+	}
+}
@@ -0,0 +1,31 @@
+package diagnostics
+
+// Record is the JSON form of a diagnostic, lines and columns
+// start at 1 and the end position is exclusive
+type Record struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Severity  string `json:"severity"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Hint      string `json:"hint,omitempty"`
+}
+
+func (d Diagnostic) Record() Record {
+	endLine, endColumn := d.End()
+
+	return Record{
+		File:      d.Token.File,
+		Line:      d.Token.Line,
+		Column:    d.Token.Column,
+		EndLine:   endLine,
+		EndColumn: endColumn,
+		Severity:  d.Severity.String(),
+		Code:      d.Code,
+		Message:   d.Message,
+		Hint:      d.Hint,
+	}
+}
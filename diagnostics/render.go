@@ -0,0 +1,121 @@
+package diagnostics
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	reset = "\x1b[0m"
+	bold  = "\x1b[1m"
+	red   = "\x1b[31m"
+	blue  = "\x1b[34m"
+	cyan  = "\x1b[36m"
+)
+
+// Renderer prints diagnostics of a single source
+// with the line they point at, like this
+//
+//	error: expected expression.
+//	 --> main.lox:1:9
+//	  |
+//	1 | var a = ;
+//	  |         ^
+type Renderer struct {
+	w     io.Writer
+	lines []string
+	// Color is enabled by NewRenderer when w is a terminal
+	Color bool
+}
+
+func NewRenderer(w io.Writer, source string) *Renderer {
+	return &Renderer{w: w, lines: strings.Split(source, "\n"), Color: isTerminal(w)}
+}
+
+// isTerminal reports whether colors can be written to w,
+// NO_COLOR (https://no-color.org) turns them off
+func isTerminal(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (r *Renderer) Render(d Diagnostic) {
+	severity := red
+
+	fmt.Fprintf(r.w, "%s: %s\n", r.paint(bold+severity, d.Severity.String()), r.paint(bold, d.Message))
+
+	tok := d.Token
+	if tok.Line > 0 {
+		gutter := strings.Repeat(" ", len(strconv.Itoa(tok.Line)))
+
+		location := fmt.Sprintf("%d", tok.Line)
+		if tok.Column > 0 {
+			location = tok.Pos()
+		} else if tok.File != "" {
+			location = fmt.Sprintf("%s:%d", tok.File, tok.Line)
+		}
+		fmt.Fprintf(r.w, "%s%s %s\n", gutter, r.paint(bold+blue, "-->"), location)
+
+		if tok.Line <= len(r.lines) {
+			line := strings.TrimRight(r.lines[tok.Line-1], "\r")
+			fmt.Fprintf(r.w, "%s %s\n", gutter, r.paint(bold+blue, "|"))
+			fmt.Fprintf(r.w, "%s %s %s\n", r.paint(bold+blue, strconv.Itoa(tok.Line)), r.paint(bold+blue, "|"), line)
+
+			if tok.Column > 0 {
+				fmt.Fprintf(r.w, "%s %s %s\n", gutter, r.paint(bold+blue, "|"), r.paint(bold+severity, underline(line, tok.Column, tok.End-tok.Start)))
+			}
+		}
+
+		if d.Hint != "" {
+			fmt.Fprintf(r.w, "%s %s %s\n", gutter, r.paint(bold+blue, "="), r.paint(cyan, "hint: "+d.Hint))
+		}
+	} else if d.Hint != "" {
+		fmt.Fprintf(r.w, "%s\n", r.paint(cyan, "hint: "+d.Hint))
+	}
+
+	if d.Trace != "" {
+		fmt.Fprint(r.w, d.Trace)
+	}
+}
+
+// underline puts carets under length bytes of the line starting at the
+// column, the span is cut at the end of the line and is never empty
+func underline(line string, column int, length int) string {
+	start := min(column-1, len(line))
+	end := min(start+length, len(line))
+
+	var b strings.Builder
+	// tabs are kept so that the carets line up with the source
+	for _, c := range line[:start] {
+		if c == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+
+	b.WriteString(strings.Repeat("^", max(utf8.RuneCountInString(line[start:end]), 1)))
+
+	return b.String()
+}
+
+func (r *Renderer) paint(style string, text string) string {
+	if !r.Color {
+		return text
+	}
+
+	return style + text + reset
+}
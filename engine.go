@@ -0,0 +1,228 @@
+// Package golox runs lox code from Go programs, an Engine
+// keeps its globals between the runs so the host can
+// define values for the scripts and read back their results
+package golox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/havrydotdev/golox/diagnostics"
+	eval "github.com/havrydotdev/golox/evaluator"
+	"github.com/havrydotdev/golox/lox"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/resolver"
+	"github.com/havrydotdev/golox/scanner"
+)
+
+// Limits bound what every Run and Call may use, see SetLimits
+type Limits = eval.Limits
+
+// Engine runs scripts on the tree-walking evaluator,
+// it isn't safe for concurrent use
+type Engine struct {
+	evaluator *eval.Evaluator
+	limits    Limits
+	// the globals set by the host, in the order they were first set
+	defined []lox.Native
+
+	stderr io.Writer
+}
+
+// New creates an engine which reads from os.Stdin, prints
+// to os.Stdout and doesn't render its errors anywhere
+func New() *Engine {
+	return &Engine{evaluator: eval.New().(*eval.Evaluator)}
+}
+
+// SetStdin sets what the scripts read from, natives
+// reach it through the Stdin of their evaluator
+func (e *Engine) SetStdin(r io.Reader) {
+	e.evaluator.SetStdin(r)
+}
+
+func (e *Engine) Stdin() io.Reader {
+	return e.evaluator.Stdin()
+}
+
+// SetStdout sets where print writes to, engines
+// with their own writers can run concurrently
+func (e *Engine) SetStdout(w io.Writer) {
+	e.evaluator.SetStdout(w)
+}
+
+// SetStderr makes the engine render the errors of
+// the runs with their source, like the golox command
+func (e *Engine) SetStderr(w io.Writer) {
+	e.stderr = w
+}
+
+// SetLimits bounds the statements, the nested calls and the
+// allocated values of every following Run and Call, going over one
+// fails them with a *StatementLimitError, *DepthLimitError or
+// *AllocationLimitError
+func (e *Engine) SetLimits(limits Limits) {
+	e.limits = limits
+}
+
+// Run runs the source, it returns a *CompileError if the source
+// has errors and a *RuntimeError if the running code fails, once
+// ctx is done the code stops at the next loop iteration or call
+// and Run returns the error of ctx
+func (e *Engine) Run(ctx context.Context, source string) error {
+	return e.run(ctx, "", source)
+}
+
+// RunFile runs the file like Run, its errors
+// and stack traces point into the file
+func (e *Engine) RunFile(ctx context.Context, fileName string) error {
+	text, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	return e.run(ctx, fileName, string(text))
+}
+
+func (e *Engine) run(ctx context.Context, fileName, source string) error {
+	e.start(ctx)
+	defer e.evaluator.SetContext(nil)
+
+	stmts, errs := e.compile(fileName, source)
+	if len(errs) != 0 {
+		err := newCompileError(errs)
+		e.report(source, err.Diagnostics...)
+		return err
+	}
+
+	for _, stmt := range stmts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := stmt.Eval(); err != nil {
+			err = hostError(err)
+			e.report(source, diagnostics.From(err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *Engine) start(ctx context.Context) {
+	e.evaluator.SetContext(ctx)
+	e.evaluator.SetLimits(e.limits)
+}
+
+// compile returns the errors of the first stage which fails
+func (e *Engine) compile(fileName, source string) ([]eval.StmtEvaluator, []error) {
+	tokens, errs := scanner.NewFile(fileName, source).Scan()
+	if len(errs) != 0 {
+		return nil, errs
+	}
+
+	res := resolver.New[eval.ExpEvaluator, eval.StmtEvaluator](e.evaluator)
+	nodes, errs := parser.New(tokens, res).Parse()
+	if len(errs) != 0 {
+		return nil, errs
+	}
+
+	return res.Resolve(nodes)
+}
+
+func (e *Engine) report(source string, ds ...diagnostics.Diagnostic) {
+	if e.stderr == nil {
+		return
+	}
+
+	renderer := diagnostics.NewRenderer(e.stderr, source)
+	for _, d := range ds {
+		renderer.Render(d)
+	}
+}
+
+// SetGlobal defines the global for the scripts which run after,
+// the value is converted to a lox value, see Register
+func (e *Engine) SetGlobal(name string, value any) error {
+	value, err := toLox(value)
+	if err != nil {
+		return err
+	}
+
+	e.evaluator.Globals().Define(name, value)
+	e.define(name, value)
+	return nil
+}
+
+// Defined describes the globals set with SetGlobal and Register,
+// for tools like the language server which only read the scripts
+func (e *Engine) Defined() []lox.Native {
+	return slices.Clone(e.defined)
+}
+
+func (e *Engine) define(name string, value any) {
+	native := lox.Native{Name: name}
+	if fun, ok := value.(eval.Callable); ok {
+		native.Params = make([]string, fun.Arity())
+		for i := range native.Params {
+			native.Params[i] = fmt.Sprintf("arg%d", i+1)
+		}
+	}
+
+	i := slices.IndexFunc(e.defined, func(other lox.Native) bool {
+		return other.Name == name
+	})
+	if i == -1 {
+		e.defined = append(e.defined, native)
+	} else {
+		e.defined[i] = native
+	}
+}
+
+// GetGlobal reads the global as a lox value,
+// numbers are float32 and nil is a nil interface
+func (e *Engine) GetGlobal(name string) (any, bool) {
+	return e.evaluator.Globals().Get(name)
+}
+
+// Call calls the global function or class with the arguments,
+// which are converted like the values of SetGlobal
+func (e *Engine) Call(ctx context.Context, name string, args ...any) (any, error) {
+	value, ok := e.GetGlobal(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUndefined, name)
+	}
+
+	fun, ok := value.(eval.Callable)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotCallable, name)
+	}
+
+	values := make([]any, len(args))
+	for i, arg := range args {
+		val, err := toLox(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = val
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	e.start(ctx)
+	defer e.evaluator.SetContext(nil)
+
+	val, err := e.evaluator.Invoke(fun, values)
+	if err != nil {
+		return nil, hostError(err)
+	}
+
+	return val, nil
+}
@@ -1,32 +1,190 @@
-package main
+package golox
 
 import (
-	_ "embed"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	eval "github.com/havrydotdev/golox/evaluator"
+	"github.com/havrydotdev/golox/lox"
 	"github.com/havrydotdev/golox/parser"
-	"github.com/havrydotdev/golox/scanner"
 )
 
-//go:embed _examples/fib_recur.lox
-var fibRecur []byte
+func TestRunAndGlobals(t *testing.T) {
+	var out bytes.Buffer
+	e := New()
+	e.SetStdout(&out)
 
-func TestFibRecursion(t *testing.T) {
-	tokens, err := scanner.New(string(fibRecur)).Scan()
+	if err := e.SetGlobal("limit", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	err := e.Run(context.Background(), `
+var total = 0;
+for (var i = 0; i < limit; i = i + 1) total = total + i;
+print("total");
+fun double(n) { return n * 2; }
+`)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+
+	if out.String() != "total\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	if total, ok := e.GetGlobal("total"); !ok || total != float32(3) {
+		t.Errorf("expected total to be 3, got %v", total)
+	}
+
+	// the state is kept between the runs
+	if err := e.Run(context.Background(), "total = double(total);"); err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := e.Call(context.Background(), "double", 21)
+	if err != nil || val != float32(42) {
+		t.Errorf("expected 42, got %v, %v", val, err)
+	}
+
+	if err := e.SetGlobal("ch", make(chan int)); err == nil {
+		t.Error("channels aren't lox values")
+	}
+
+	e.SetGlobal("limit", 4)
+	e.SetGlobal("add", func(a, b int) int { return a + b })
+	expected := []lox.Native{{Name: "limit"}, {Name: "add", Params: []string{"arg1", "arg2"}}}
+	if defined := e.Defined(); !reflect.DeepEqual(defined, expected) {
+		t.Errorf("expected the globals %v, got %v", expected, defined)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var stderr bytes.Buffer
+	e := New()
+	e.SetStderr(&stderr)
+
+	err := e.Run(context.Background(), "var a = ;\nvar b = ;")
+	var compileErr *CompileError
+	var parseErr parser.Error
+	if !errors.As(err, &compileErr) || len(compileErr.Diagnostics) != 2 || !errors.As(err, &parseErr) {
+		t.Errorf("expected two syntax errors, got %v", err)
+	}
+
+	if !strings.Contains(stderr.String(), "expected expression") {
+		t.Errorf("expected the errors to be rendered, got %q", stderr.String())
+	}
+
+	err = e.Run(context.Background(), "fun f() { return -\"a\"; }\nf();")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Diagnostic.Token.Line != 1 {
+		t.Errorf("expected a runtime error on line 1, got %v", err)
+	}
+
+	var evalErr *eval.RuntimeError
+	if !errors.As(err, &evalErr) || len(evalErr.Frames) != 2 {
+		t.Errorf("expected the frames of f and the script, got %v", evalErr)
+	}
+
+	if _, err := e.Call(context.Background(), "missing"); !errors.Is(err, ErrUndefined) || err.Error() != "undefined global: missing" {
+		t.Errorf("expected an undefined global, got %v", err)
 	}
 
-	exprs, errs := parser.New(tokens, eval.New()).Parse()
-	for _, err := range errs {
-		t.Error(err)
+	e.SetGlobal("n", 1)
+	if _, err := e.Call(context.Background(), "n"); !errors.Is(err, ErrNotCallable) {
+		t.Errorf("expected a value which isn't callable, got %v", err)
+	}
+
+	if _, err := e.Call(context.Background(), "f", 1); !errors.As(err, &runtimeErr) {
+		t.Errorf("expected an arity error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := e.Run(ctx, "print(1);"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the run to be canceled, got %v", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	e := New()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := e.Run(ctx, "while (true) {}"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the loop to time out, got %v", err)
+	}
+
+	e.Run(context.Background(), "fun forever() { forever(); }")
+	if err := e.Run(context.Background(), "forever();"); !errors.As(err, new(*DepthLimitError)) {
+		t.Errorf("expected the recursion to be stopped, got %v", err)
+	}
+
+	e.SetLimits(Limits{Statements: 100, Depth: 10, Allocations: 10})
+	for source, target := range map[string]any{
+		"for (var i = 0; i < 100; i = i + 1) {}":                         new(*StatementLimitError),
+		"fun f(n) { if (n > 0) f(n - 1); } f(10);":                       new(*DepthLimitError),
+		"var xs = []; for (var i = 0; i < 10; i = i + 1) push(xs, [i]);": new(*AllocationLimitError),
+	} {
+		if err := e.Run(context.Background(), source); !errors.As(err, target) {
+			t.Errorf("%s: expected %T, got %v", source, target, err)
+		}
+	}
+
+	// the values natives create are counted too
+	e.SetLimits(Limits{Allocations: 5})
+	e.SetGlobal("m", map[string]int{"a": 1})
+	for _, native := range []string{"keys(m)", "values(m)", "str(1)"} {
+		source := "for (var i = 0; i < 10; i = i + 1) " + native + ";"
+		if err := e.Run(context.Background(), source); !errors.As(err, new(*AllocationLimitError)) {
+			t.Errorf("%s: expected the allocations to be limited, got %v", native, err)
+		}
+	}
+
+	// functions parsed before the limits were set are counted too
+	e.SetLimits(Limits{})
+	e.Run(context.Background(), "fun down(n) { if (n > 0) down(n - 1); }")
+	e.SetLimits(Limits{Statements: 50})
+	if _, err := e.Call(context.Background(), "down", 100); !errors.As(err, new(*StatementLimitError)) {
+		t.Errorf("expected the calls to run out of statements, got %v", err)
+	}
+
+	// the limits are for every run, not all of them
+	for range 3 {
+		if err := e.Run(context.Background(), "for (var i = 0; i < 10; i = i + 1) {}"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConcurrentOutput(t *testing.T) {
+	var wg sync.WaitGroup
+	outs := make([]bytes.Buffer, 8)
+	for i := range outs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			e := New()
+			e.SetStdout(&outs[i])
+			e.SetGlobal("id", i)
+			if err := e.Run(context.Background(), "for (var i = 0; i < 100; i = i + 1) print(id);"); err != nil {
+				t.Error(err)
+			}
+		}()
 	}
 
-	for _, expr := range exprs {
-		err := expr.Eval()
-		if err != nil {
-			t.Error(err)
+	wg.Wait()
+	for i := range outs {
+		expected := strings.Repeat(fmt.Sprintf("%d\n", i), 100)
+		if outs[i].String() != expected {
+			t.Errorf("engine %d printed %q", i, outs[i].String())
 		}
 	}
 }
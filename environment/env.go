@@ -1,6 +1,7 @@
 package env
 
-// TODO: fix resolving
+import "sort"
+
 type Env struct {
 	outer *Env
 
@@ -41,3 +42,48 @@ func (e *Env) Get(name string) (any, bool) {
 
 	return val, ok
 }
+
+// GetAt reads the name from the environment
+// which is depth scopes above this one
+func (e *Env) GetAt(depth int, name string) (any, bool) {
+	val, ok := e.ancestor(depth).values[name]
+	return val, ok
+}
+
+// AssignAt assigns the name in the environment
+// which is depth scopes above this one
+func (e *Env) AssignAt(depth int, name string, value any) bool {
+	env := e.ancestor(depth)
+	if _, ok := env.values[name]; !ok {
+		return false
+	}
+
+	env.values[name] = value
+	return true
+}
+
+func (e *Env) ancestor(depth int) *Env {
+	env := e
+	for range depth {
+		env = env.outer
+	}
+
+	return env
+}
+
+// Outer is the enclosing environment, it is nil for the globals
+func (e *Env) Outer() *Env {
+	return e.outer
+}
+
+// Names are the sorted names defined in this environment
+// without the ones of the enclosing environments
+func (e *Env) Names() []string {
+	names := make([]string, 0, len(e.values))
+	for name := range e.values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,90 @@
+package golox
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/havrydotdev/golox/diagnostics"
+	eval "github.com/havrydotdev/golox/evaluator"
+)
+
+var (
+	ErrUndefined   = errors.New("undefined global")
+	ErrNotCallable = errors.New("can only call functions and classes")
+)
+
+// the errors of going over the Limits
+type (
+	StatementLimitError  = eval.StatementLimitError
+	DepthLimitError      = eval.DepthLimitError
+	AllocationLimitError = eval.AllocationLimitError
+)
+
+// CompileError is returned when the source doesn't scan, parse or
+// resolve, none of it runs then, Diagnostics has every error
+// of the stage which failed in the order they were found
+type CompileError struct {
+	Diagnostics []diagnostics.Diagnostic
+
+	errs []error
+}
+
+func newCompileError(errs []error) *CompileError {
+	ds := make([]diagnostics.Diagnostic, len(errs))
+	for i, err := range errs {
+		ds[i] = diagnostics.From(err)
+	}
+
+	return &CompileError{ds, errs}
+}
+
+func (e *CompileError) Error() string {
+	messages := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns the errors of the stage,
+// for errors.As to reach for example a parser.Error
+func (e *CompileError) Unwrap() []error {
+	return e.errs
+}
+
+// RuntimeError is returned when the running code fails,
+// the diagnostic has the position and the stack trace
+type RuntimeError struct {
+	Diagnostic diagnostics.Diagnostic
+
+	err error
+}
+
+func newRuntimeError(err error) *RuntimeError {
+	return &RuntimeError{diagnostics.From(err), err}
+}
+
+func (e *RuntimeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.err
+}
+
+// hostError turns the error of the evaluator into the one the
+// host gets, interrupts are the error of the context and the
+// errors of the limits are returned as they are
+func hostError(err error) error {
+	var interrupt *eval.Interrupt
+	if errors.As(err, &interrupt) {
+		return interrupt.Err
+	}
+
+	if _, ok := err.(*eval.RuntimeError); ok {
+		return newRuntimeError(err)
+	}
+
+	return err
+}
@@ -2,6 +2,7 @@ package eval
 
 import (
 	env "github.com/havrydotdev/golox/environment"
+	"github.com/havrydotdev/golox/lox"
 	"github.com/havrydotdev/golox/token"
 )
 
@@ -16,32 +17,96 @@ type NativeFun struct {
 }
 
 type Function struct {
+	name    token.Token
 	params  []token.Token
 	body    []StmtEvaluator
 	closure *env.Env
+
+	// initializers always return "this"
+	isInitializer bool
 }
 
 func NewNativeFun(arity uint8, call func(e *Evaluator, args []any) (any, error)) Callable {
-	return NativeFun{arity, call}
+	return &NativeFun{arity, call}
 }
 
-func (c NativeFun) Arity() uint8 {
+func (c *NativeFun) Arity() uint8 {
 	return c.arity
 }
 
-func (c NativeFun) Call(e *Evaluator, args []any) (any, error) {
-	return c.call(e, args)
+// Call counts the lists, maps and strings natives return as
+// allocated, like the ones lox code creates, even when one
+// was already there like the element pop returns
+func (c *NativeFun) Call(e *Evaluator, args []any) (any, error) {
+	val, err := c.call(e, args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch val.(type) {
+	case *lox.List, *lox.Map, string:
+		return val, e.allocate()
+	}
+
+	return val, nil
+}
+
+func (c *NativeFun) String() string {
+	return "<native fn>"
+}
+
+// Bind returns a copy of the method whose closure
+// has "this" defined as the given instance
+func (f *Function) Bind(inst *Instance) *Function {
+	env := env.NewChild(f.closure)
+	env.Define("this", inst)
+
+	return &Function{f.name, f.params, f.body, env, f.isInitializer}
 }
 
-func (f Function) Arity() uint8 {
+func (f *Function) Arity() uint8 {
 	return uint8(len(f.params))
 }
 
-func (f Function) Call(e *Evaluator, args []any) (any, error) {
+func (f *Function) Call(e *Evaluator, args []any) (any, error) {
 	env := env.NewChild(f.closure)
 	for i, param := range f.params {
 		env.Define(param.Lexeme, args[i])
 	}
 
-	return e.executeBlock(f.body, env)
+	err := e.executeBlock(f.body, env)
+	ret, isReturn := err.(Return)
+	if err != nil && !isReturn {
+		return nil, err
+	}
+
+	if f.isInitializer {
+		this, _ := f.closure.Get("this")
+		return this, nil
+	}
+
+	return ret.value, nil
+}
+
+func (f *Function) String() string {
+	return "<fn " + f.name.Lexeme + ">"
+}
+
+// Invoke calls the callable from Go, outside of the lox code,
+// its errors are runtime errors like the ones of lox calls
+func (e *Evaluator) Invoke(fun Callable, args []any) (any, error) {
+	return e.call(fun, token.Token{}, args)
+}
+
+// callName is the name of the callable in stack traces
+func callName(fun Callable) string {
+	switch fun := fun.(type) {
+	case *Function:
+		return fun.name.Lexeme
+	case *Class:
+		// only the initializer of a class can fail
+		return "init"
+	}
+
+	return "native"
 }
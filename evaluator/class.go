@@ -1,17 +1,50 @@
 package eval
 
 type Class struct {
-	Name string
+	Name       string
+	superclass *Class
+	methods    map[string]*Function
 }
 
-func (c Class) Arity() uint8 {
-	return 0
+// FindMethod looks the method up in the class
+// and then walks the superclass chain
+func (c *Class) FindMethod(name string) (*Function, bool) {
+	method, ok := c.methods[name]
+	if !ok && c.superclass != nil {
+		return c.superclass.FindMethod(name)
+	}
+
+	return method, ok
+}
+
+// Arity of a class is the arity of its initializer
+func (c *Class) Arity() uint8 {
+	init, ok := c.FindMethod("init")
+	if !ok {
+		return 0
+	}
+
+	return init.Arity()
 }
 
-func (c Class) Call(e *Evaluator, args []any) (any, error) {
-	return Instance{Class: c, fields: map[string]any{"hello": ""}}, nil
+func (c *Class) Call(e *Evaluator, args []any) (any, error) {
+	if err := e.allocate(); err != nil {
+		return nil, err
+	}
+
+	inst := &Instance{Class: c, fields: make(map[string]any)}
+
+	init, ok := c.FindMethod("init")
+	if ok {
+		_, err := init.Bind(inst).Call(e, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return inst, nil
 }
 
-func (c Class) String() string {
+func (c *Class) String() string {
 	return c.Name
 }
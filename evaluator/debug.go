@@ -0,0 +1,68 @@
+package eval
+
+import (
+	env "github.com/havrydotdev/golox/environment"
+	"github.com/havrydotdev/golox/token"
+)
+
+// Hook runs before every statement the parser located, start is
+// the first token of the statement, the program waits for the hook
+// to return and unwinds with an Interrupt if it fails
+type Hook func(e *Evaluator, start token.Token) error
+
+// Frame is a running call, Token is where it is and Env
+// is the innermost environment of its code
+type Frame struct {
+	Function string
+	Token    token.Token
+	Env      *env.Env
+}
+
+// SetHook installs the hook, a nil hook removes it
+func (e *Evaluator) SetHook(hook Hook) {
+	e.hook = hook
+}
+
+// At implements interp.Positioned, every located statement is
+// counted and runs the hook, both are looked up when it runs so
+// code parsed before a limit or a hook was set is covered too
+func (e *Evaluator) At(start token.Token, stmt StmtEvaluator) StmtEvaluator {
+	return stmtEvalFunc(func() error {
+		if err := e.countStatement(); err != nil {
+			return err
+		}
+
+		if hook := e.hook; hook != nil {
+			if err := hook(e, start); err != nil {
+				return &Interrupt{err}
+			}
+		}
+
+		return stmt.Eval()
+	})
+}
+
+// Depth is the number of calls which are running
+func (e *Evaluator) Depth() int {
+	return len(e.calls)
+}
+
+// Stack are the frames of the calls which are running, the most
+// recent first, at is the position in the innermost one, the
+// last frame is the top-level code, which is named "script"
+func (e *Evaluator) Stack(at token.Token) []Frame {
+	frames := make([]Frame, 0, len(e.calls)+1)
+
+	environment := e.environment
+	for i := len(e.calls) - 1; i >= 0; i-- {
+		frames = append(frames, Frame{e.calls[i].function, at, environment})
+		at, environment = e.calls[i].paren, e.calls[i].environment
+	}
+
+	return append(frames, Frame{"script", at, environment})
+}
+
+// Globals is the environment of the top-level code and the natives
+func (e *Evaluator) Globals() *env.Env {
+	return e.globals
+}
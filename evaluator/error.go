@@ -0,0 +1,70 @@
+package eval
+
+import (
+	"fmt"
+
+	env "github.com/havrydotdev/golox/environment"
+	"github.com/havrydotdev/golox/lox"
+	"github.com/havrydotdev/golox/token"
+)
+
+// RuntimeError is an error raised by the running code,
+// Token is where it happened and Frames is the call stack
+type RuntimeError struct {
+	Token   token.Token
+	Message string
+	Frames  []lox.Frame
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("[line %d] %s", e.Token.Line, e.Message)
+}
+
+func (e *RuntimeError) Trace() string {
+	return lox.Trace(e.Frames)
+}
+
+// Interrupt is returned when the hook fails or the context is
+// done, it unwinds the program without being turned into a runtime error
+type Interrupt struct {
+	Err error
+}
+
+func (i *Interrupt) Error() string {
+	return "interrupted: " + i.Err.Error()
+}
+
+func (i *Interrupt) Unwrap() error {
+	return i.Err
+}
+
+// call is a lox function call which is running, paren is the
+// token the call was made at and environment the caller's one
+type call struct {
+	function    string
+	paren       token.Token
+	environment *env.Env
+}
+
+// error creates a runtime error at the token
+// with the frames of the calls which are running
+func (e *Evaluator) error(tok token.Token, format string, args ...any) *RuntimeError {
+	stack := e.Stack(tok)
+	frames := make([]lox.Frame, len(stack))
+	for i, frame := range stack {
+		frames[i] = lox.Frame{Function: frame.Function, File: frame.Token.File, Line: frame.Token.Line}
+	}
+
+	return &RuntimeError{Token: tok, Message: fmt.Sprintf(format, args...), Frames: frames}
+}
+
+// wrap turns errors of natives and lox values
+// into runtime errors raised at the token
+func (e *Evaluator) wrap(tok token.Token, err error) error {
+	switch err.(type) {
+	case nil, *RuntimeError, *Interrupt, *StatementLimitError, *DepthLimitError, *AllocationLimitError:
+		return err
+	}
+
+	return e.error(tok, "%s", err.Error())
+}
@@ -1,11 +1,14 @@
 package eval
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"io"
+	"os"
 
 	env "github.com/havrydotdev/golox/environment"
 	interp "github.com/havrydotdev/golox/interpreter"
+	"github.com/havrydotdev/golox/lox"
 	"github.com/havrydotdev/golox/token"
 )
 
@@ -13,6 +16,9 @@ var (
 	ErrNilValue = errors.New("internal error: exp/stmt is nil, cannot invoke")
 )
 
+// depth of expressions that were not resolved statically
+const unresolved = -1
+
 type Return struct {
 	value any
 }
@@ -21,6 +27,20 @@ func (r Return) Error() string {
 	return "return statement"
 }
 
+// Break and Continue unwind the statements
+// of a loop body the same way Return does
+type Break struct{}
+
+func (Break) Error() string {
+	return "break statement"
+}
+
+type Continue struct{}
+
+func (Continue) Error() string {
+	return "continue statement"
+}
+
 // TODO: add special type for lox objects
 type ExpEvaluator interface {
 	Eval() (any, error)
@@ -33,6 +53,29 @@ type StmtEvaluator interface {
 type expEvalFunc func() (any, error)
 type stmtEvalFunc func() error
 
+// funcDecl is the statement built for function declarations,
+// classes use it to collect their methods instead of
+// defining them in the current environment
+type funcDecl struct {
+	e      *Evaluator
+	name   token.Token
+	params []token.Token
+	body   []StmtEvaluator
+}
+
+func (d *funcDecl) Eval() error {
+	if err := d.e.allocate(); err != nil {
+		return err
+	}
+
+	d.e.environment.Define(d.name.Lexeme, d.function(d.e.environment))
+	return nil
+}
+
+func (d *funcDecl) function(closure *env.Env) *Function {
+	return &Function{name: d.name, params: d.params, body: d.body, closure: closure}
+}
+
 func (fn expEvalFunc) Eval() (any, error) {
 	return fn()
 }
@@ -44,12 +87,42 @@ func (fn stmtEvalFunc) Eval() error {
 type Evaluator struct {
 	globals     *env.Env
 	environment *env.Env
+
+	stdin  io.Reader
+	stdout io.Writer
+
+	calls []call
+	hook  Hook
+
+	ctx    context.Context
+	done   <-chan struct{}
+	limits Limits
+	usage  usage
 }
 
 func New() interp.Alg[ExpEvaluator, StmtEvaluator] {
 	globals := newGlobals()
 
-	return &Evaluator{environment: globals, globals: globals}
+	return &Evaluator{environment: globals, globals: globals, stdin: os.Stdin, stdout: os.Stdout}
+}
+
+// SetStdin sets what natives read from, it is os.Stdin by default
+func (e *Evaluator) SetStdin(r io.Reader) {
+	e.stdin = r
+}
+
+func (e *Evaluator) Stdin() io.Reader {
+	return e.stdin
+}
+
+// SetStdout sets where print and other natives
+// write to, it is os.Stdout by default
+func (e *Evaluator) SetStdout(w io.Writer) {
+	e.stdout = w
+}
+
+func (e *Evaluator) Stdout() io.Writer {
+	return e.stdout
 }
 
 func (e *Evaluator) Set(object ExpEvaluator, name token.Token, value ExpEvaluator) ExpEvaluator {
@@ -59,9 +132,10 @@ func (e *Evaluator) Set(object ExpEvaluator, name token.Token, value ExpEvaluato
 			return nil, err
 		}
 
-		inst, ok := obj.(Instance)
-		if !ok {
-			return nil, errors.New("only instances have fields")
+		inst, isInstance := obj.(*Instance)
+		host, isObject := obj.(Object)
+		if !isInstance && !isObject {
+			return nil, e.error(name, "only instances have fields")
 		}
 
 		val, err := value.Eval()
@@ -69,7 +143,100 @@ func (e *Evaluator) Set(object ExpEvaluator, name token.Token, value ExpEvaluato
 			return nil, err
 		}
 
-		inst.Set(name.Lexeme, val)
+		if isInstance {
+			inst.Set(name.Lexeme, val)
+			return val, nil
+		}
+
+		return val, e.wrap(name, host.Set(name.Lexeme, val))
+	})
+}
+
+func (e *Evaluator) List(bracket token.Token, elements []ExpEvaluator) ExpEvaluator {
+	return expEvalFunc(func() (any, error) {
+		values := make([]any, len(elements))
+		for i, element := range elements {
+			val, err := element.Eval()
+			if err != nil {
+				return nil, err
+			}
+
+			values[i] = val
+		}
+
+		return lox.NewList(values), e.allocate()
+	})
+}
+
+func (e *Evaluator) Map(brace token.Token, keys []ExpEvaluator, values []ExpEvaluator) ExpEvaluator {
+	return expEvalFunc(func() (any, error) {
+		if err := e.allocate(); err != nil {
+			return nil, err
+		}
+
+		m := lox.NewMap()
+		for i := range keys {
+			key, err := keys[i].Eval()
+			if err != nil {
+				return nil, err
+			}
+
+			val, err := values[i].Eval()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := m.Set(key, val); err != nil {
+				return nil, e.error(brace, "%s", err.Error())
+			}
+		}
+
+		return m, nil
+	})
+}
+
+func (e *Evaluator) Index(object ExpEvaluator, bracket token.Token, index ExpEvaluator) ExpEvaluator {
+	return expEvalFunc(func() (any, error) {
+		obj, err := object.Eval()
+		if err != nil {
+			return nil, err
+		}
+
+		idx, err := index.Eval()
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := lox.Index(obj, idx)
+		if err != nil {
+			return nil, e.error(bracket, "%s", err.Error())
+		}
+
+		return val, nil
+	})
+}
+
+func (e *Evaluator) SetIndex(object ExpEvaluator, bracket token.Token, index ExpEvaluator, value ExpEvaluator) ExpEvaluator {
+	return expEvalFunc(func() (any, error) {
+		obj, err := object.Eval()
+		if err != nil {
+			return nil, err
+		}
+
+		idx, err := index.Eval()
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := value.Eval()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := lox.SetIndex(obj, idx, val); err != nil {
+			return nil, e.error(bracket, "%s", err.Error())
+		}
+
 		return val, nil
 	})
 }
@@ -81,27 +248,126 @@ func (e *Evaluator) Get(name token.Token, expr ExpEvaluator) ExpEvaluator {
 			return nil, err
 		}
 
-		inst, ok := rawInst.(Instance)
-		if !ok {
-			return nil, errors.New("only instances have properties.")
+		var (
+			val any
+			ok  bool
+		)
+		switch obj := rawInst.(type) {
+		case *Instance:
+			val, ok = obj.Get(name.Lexeme)
+		case Object:
+			val, ok = obj.Get(name.Lexeme)
+		default:
+			return nil, e.error(name, "only instances have properties")
 		}
 
-		val, ok := inst.Get(name.Lexeme)
 		if !ok {
-			return nil, errors.New("unknown key")
+			return nil, e.error(name, "undefined property %s", name.Lexeme)
 		}
 
 		return val, nil
 	})
 }
 
-func (e *Evaluator) Class(name token.Token, methods []StmtEvaluator) StmtEvaluator {
+func (e *Evaluator) Class(name token.Token, superclass ExpEvaluator, methods []StmtEvaluator) StmtEvaluator {
 	return stmtEvalFunc(func() error {
-		e.environment.Define(name.Lexeme, Class{Name: name.Lexeme})
+		var super *Class
+		closure := e.environment
+		if superclass != nil {
+			if v, ok := superclass.(*variableExpr); ok && v.name.Lexeme == name.Lexeme {
+				return e.error(v.name, "class %s can't inherit from itself", name.Lexeme)
+			}
+
+			val, err := superclass.Eval()
+			if err != nil {
+				return err
+			}
+
+			class, ok := val.(*Class)
+			if !ok {
+				return e.error(name, "superclass of %s must be a class", name.Lexeme)
+			}
+
+			super = class
+
+			// methods close over an environment
+			// where "super" is the superclass
+			closure = env.NewChild(e.environment)
+			closure.Define("super", super)
+		}
+
+		class := &Class{Name: name.Lexeme, superclass: super, methods: make(map[string]*Function)}
+		for _, method := range methods {
+			decl, ok := method.(*funcDecl)
+			if !ok {
+				return e.error(name, "invalid method in class %s", name.Lexeme)
+			}
+
+			method := decl.function(closure)
+			method.isInitializer = decl.name.Lexeme == "init"
+			class.methods[decl.name.Lexeme] = method
+		}
+
+		e.environment.Define(name.Lexeme, class)
 		return nil
 	})
 }
 
+type thisExpr struct {
+	e       *Evaluator
+	keyword token.Token
+	depth   int
+}
+
+func (t *thisExpr) Eval() (any, error) {
+	val, ok := t.e.lookUp(t.keyword.Lexeme, t.depth)
+	if !ok {
+		return nil, t.e.error(t.keyword, "can't use 'this' outside of a class")
+	}
+
+	return val, nil
+}
+
+func (e *Evaluator) This(keyword token.Token) ExpEvaluator {
+	return &thisExpr{e, keyword, unresolved}
+}
+
+type superExpr struct {
+	e       *Evaluator
+	keyword token.Token
+	method  token.Token
+	depth   int
+}
+
+func (s *superExpr) Eval() (any, error) {
+	val, ok := s.e.lookUp(s.keyword.Lexeme, s.depth)
+	if !ok {
+		return nil, s.e.error(s.keyword, "can't use 'super' outside of a subclass")
+	}
+
+	// "this" is always defined one scope inside of "super"
+	depth := s.depth
+	if depth != unresolved {
+		depth--
+	}
+
+	inst, ok := s.e.lookUp("this", depth)
+	if !ok {
+		return nil, s.e.error(s.keyword, "can't use 'super' outside of a method")
+	}
+
+	fun, ok := val.(*Class).FindMethod(s.method.Lexeme)
+	if !ok {
+		return nil, s.e.error(s.method, "undefined property %s", s.method.Lexeme)
+	}
+
+	return fun.Bind(inst.(*Instance)), nil
+}
+
+func (e *Evaluator) Super(keyword token.Token, method token.Token) ExpEvaluator {
+	return &superExpr{e, keyword, method, unresolved}
+}
+
 func (e *Evaluator) Return(keyword token.Token, value ExpEvaluator) StmtEvaluator {
 	return stmtEvalFunc(func() error {
 		var val any
@@ -119,10 +385,7 @@ func (e *Evaluator) Return(keyword token.Token, value ExpEvaluator) StmtEvaluato
 }
 
 func (e *Evaluator) Function(name token.Token, params []token.Token, body []StmtEvaluator) StmtEvaluator {
-	return stmtEvalFunc(func() error {
-		e.environment.Define(name.Lexeme, Function{params, body, e.environment})
-		return nil
-	})
+	return &funcDecl{e, name, params, body}
 }
 
 func (e *Evaluator) Call(callee ExpEvaluator, paren token.Token, args []ExpEvaluator) ExpEvaluator {
@@ -144,20 +407,41 @@ func (e *Evaluator) Call(callee ExpEvaluator, paren token.Token, args []ExpEvalu
 
 		fun, ok := callee.(Callable)
 		if !ok {
-			return nil, errors.New("callee is not callable")
+			return nil, e.error(paren, "can only call functions and classes")
 		}
 
-		if len(arguments) != int(fun.Arity()) {
-			return nil, fmt.Errorf("expected %d arguments, got %d", fun.Arity(), len(arguments))
-		}
-
-		return fun.Call(e, arguments)
+		return e.call(fun, paren, arguments)
 	})
 }
 
+// call runs the callable as a call made at paren
+func (e *Evaluator) call(fun Callable, paren token.Token, args []any) (any, error) {
+	if len(args) != int(fun.Arity()) {
+		return nil, e.error(paren, "expected %d arguments, got %d", fun.Arity(), len(args))
+	}
+
+	if err := e.interrupted(); err != nil {
+		return nil, err
+	}
+
+	if limit := e.depthLimit(); len(e.calls) >= limit {
+		return nil, &DepthLimitError{limit}
+	}
+
+	e.calls = append(e.calls, call{callName(fun), paren, e.environment})
+	val, err := fun.Call(e, args)
+	e.calls = e.calls[:len(e.calls)-1]
+
+	return val, e.wrap(paren, err)
+}
+
 func (e *Evaluator) While(cond ExpEvaluator, body StmtEvaluator) StmtEvaluator {
 	return stmtEvalFunc(func() error {
 		for {
+			if err := e.iterate(); err != nil {
+				return err
+			}
+
 			c, err := cond.Eval()
 			if err != nil {
 				return err
@@ -167,16 +451,94 @@ func (e *Evaluator) While(cond ExpEvaluator, body StmtEvaluator) StmtEvaluator {
 				break
 			}
 
-			err = body.Eval()
+			stop, err := loopBody(body)
 			if err != nil {
 				return err
 			}
+
+			if stop {
+				break
+			}
+		}
+
+		return nil
+	})
+}
+
+func (e *Evaluator) For(init StmtEvaluator, cond ExpEvaluator, incr ExpEvaluator, body StmtEvaluator) StmtEvaluator {
+	return stmtEvalFunc(func() error {
+		prev := e.environment
+		e.environment = env.NewChild(e.environment)
+		defer func() { e.environment = prev }()
+
+		if init != nil {
+			if err := init.Eval(); err != nil {
+				return err
+			}
+		}
+
+		for {
+			if err := e.iterate(); err != nil {
+				return err
+			}
+
+			if cond != nil {
+				c, err := cond.Eval()
+				if err != nil {
+					return err
+				}
+
+				if !isTruthy(c) {
+					break
+				}
+			}
+
+			stop, err := loopBody(body)
+			if err != nil {
+				return err
+			}
+
+			if stop {
+				break
+			}
+
+			if incr != nil {
+				if _, err := incr.Eval(); err != nil {
+					return err
+				}
+			}
 		}
 
 		return nil
 	})
 }
 
+// loopBody runs one iteration of the loop,
+// it reports whether the loop was broken out of
+func loopBody(body StmtEvaluator) (bool, error) {
+	err := body.Eval()
+	switch err.(type) {
+	case nil, Continue:
+		return false, nil
+	case Break:
+		return true, nil
+	default:
+		return false, err
+	}
+}
+
+func (*Evaluator) Break(keyword token.Token) StmtEvaluator {
+	return stmtEvalFunc(func() error {
+		return Break{}
+	})
+}
+
+func (*Evaluator) Continue(keyword token.Token) StmtEvaluator {
+	return stmtEvalFunc(func() error {
+		return Continue{}
+	})
+}
+
 func (e *Evaluator) Logical(op token.Token, left, right ExpEvaluator) ExpEvaluator {
 	return expEvalFunc(func() (any, error) {
 		l, err := left.Eval()
@@ -217,57 +579,99 @@ func (e *Evaluator) If(cond ExpEvaluator, then StmtEvaluator, _else StmtEvaluato
 
 func (e *Evaluator) Block(stmts []StmtEvaluator) StmtEvaluator {
 	return stmtEvalFunc(func() error {
-		_, err := e.executeBlock(stmts, env.NewChild(e.environment))
-		return err
+		return e.executeBlock(stmts, env.NewChild(e.environment))
 	})
 }
 
-func (e *Evaluator) executeBlock(stmts []StmtEvaluator, environment *env.Env) (any, error) {
+// executeBlock runs statements in the given environment,
+// Return is passed through so that it can reach the function call
+func (e *Evaluator) executeBlock(stmts []StmtEvaluator, environment *env.Env) error {
 	prev := e.environment
 	e.environment = environment
 	defer func() { e.environment = prev }()
 
 	for _, stmt := range stmts {
-		err := stmt.Eval()
-
-		ret, ok := err.(Return)
-		if ok {
-			return ret.value, nil
+		if err := stmt.Eval(); err != nil {
+			return err
 		}
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	return nil
+}
+
+type assignExpr struct {
+	e     *Evaluator
+	name  token.Token
+	value ExpEvaluator
+	depth int
+}
+
+func (a *assignExpr) Eval() (any, error) {
+	val, err := a.value.Eval()
+	if err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	if a.depth == unresolved {
+		ok = a.e.environment.Assign(a.name.Lexeme, val)
+	} else {
+		ok = a.e.environment.AssignAt(a.depth, a.name.Lexeme, val)
 	}
 
-	return nil, nil
+	if !ok {
+		return nil, a.e.error(a.name, "undefined variable %s", a.name.Lexeme)
+	}
+
+	return val, nil
 }
 
 func (e *Evaluator) Assign(name token.Token, value ExpEvaluator) ExpEvaluator {
-	return expEvalFunc(func() (any, error) {
-		val, err := value.Eval()
-		if err != nil {
-			return nil, err
-		}
+	return &assignExpr{e, name, value, unresolved}
+}
 
-		ok := e.environment.Assign(name.Lexeme, val)
-		if !ok {
-			return nil, fmt.Errorf("undefined variable %s", name.Lexeme)
-		}
+// variableExpr is kept as a distinct type so that
+// declarations can tell which variable they refer to
+type variableExpr struct {
+	e     *Evaluator
+	name  token.Token
+	depth int
+}
 
-		return val, nil
-	})
+func (v *variableExpr) Eval() (any, error) {
+	val, ok := v.e.lookUp(v.name.Lexeme, v.depth)
+	if !ok {
+		return nil, v.e.error(v.name, "undefined variable %s", v.name.Lexeme)
+	}
+
+	return val, nil
 }
 
 func (e *Evaluator) Variable(name token.Token) ExpEvaluator {
-	return expEvalFunc(func() (any, error) {
-		val, ok := e.environment.Get(name.Lexeme)
-		if !ok {
-			return nil, fmt.Errorf("undefined variable %s", name.Lexeme)
-		}
+	return &variableExpr{e, name, unresolved}
+}
 
-		return val, nil
-	})
+// Resolve implements interp.Resolvable, expressions that
+// were never resolved fall back to searching the environments
+func (e *Evaluator) Resolve(expr ExpEvaluator, depth int) {
+	switch expr := expr.(type) {
+	case *variableExpr:
+		expr.depth = depth
+	case *assignExpr:
+		expr.depth = depth
+	case *thisExpr:
+		expr.depth = depth
+	case *superExpr:
+		expr.depth = depth
+	}
+}
+
+func (e *Evaluator) lookUp(name string, depth int) (any, bool) {
+	if depth == unresolved {
+		return e.environment.Get(name)
+	}
+
+	return e.environment.GetAt(depth, name)
 }
 
 // this method is used as nil value in parser
@@ -309,7 +713,7 @@ func (*Evaluator) Grouping(expr ExpEvaluator) ExpEvaluator {
 	})
 }
 
-func (*Evaluator) Unary(op token.Token, right ExpEvaluator) ExpEvaluator {
+func (e *Evaluator) Unary(op token.Token, right ExpEvaluator) ExpEvaluator {
 	return expEvalFunc(func() (any, error) {
 		right, err := right.Eval()
 		if err != nil {
@@ -320,7 +724,7 @@ func (*Evaluator) Unary(op token.Token, right ExpEvaluator) ExpEvaluator {
 		case token.Minus:
 			rfloat, ok := right.(float32)
 			if !ok {
-				return nil, fmt.Errorf("Expected number, got %v", right)
+				return nil, e.error(op, "expected number, got %s", lox.Stringify(right))
 			}
 
 			return -rfloat, nil
@@ -328,11 +732,11 @@ func (*Evaluator) Unary(op token.Token, right ExpEvaluator) ExpEvaluator {
 			return !isTruthy(right), nil
 		}
 
-		return nil, fmt.Errorf("Unexpected operator %s", op.Lexeme)
+		return nil, e.error(op, "unexpected operator %s", op.Lexeme)
 	})
 }
 
-func (*Evaluator) Binary(op token.Token, left, right ExpEvaluator) ExpEvaluator {
+func (e *Evaluator) Binary(op token.Token, left, right ExpEvaluator) ExpEvaluator {
 	return expEvalFunc(func() (any, error) {
 		l, err := left.Eval()
 		if err != nil {
@@ -344,20 +748,29 @@ func (*Evaluator) Binary(op token.Token, left, right ExpEvaluator) ExpEvaluator
 			return nil, err
 		}
 
+		// equality is defined for every pair of values,
+		// the same way map keys are compared
+		switch op.Kind {
+		case token.BangEqual:
+			return !isEqual(l, r), nil
+		case token.EqualEqual:
+			return isEqual(l, r), nil
+		}
+
 		switch lparsed := l.(type) {
 		case string:
 			rparsed, ok := r.(string)
 			if !ok {
-				return nil, fmt.Errorf("expected string, got %v", r)
+				return nil, e.error(op, "expected string, got %s", lox.Stringify(r))
 			}
 
 			if op.Kind == token.Plus {
-				return lparsed + rparsed, nil
+				return lparsed + rparsed, e.allocate()
 			}
 		case float32:
 			rparsed, ok := r.(float32)
 			if !ok {
-				return nil, fmt.Errorf("expected number, got %v", r)
+				return nil, e.error(op, "expected number, got %s", lox.Stringify(r))
 			}
 
 			switch op.Kind {
@@ -370,11 +783,6 @@ func (*Evaluator) Binary(op token.Token, left, right ExpEvaluator) ExpEvaluator
 			case token.LessEqual:
 				return lparsed <= rparsed, err
 
-			case token.BangEqual:
-				return !isEqual(l, r), nil
-			case token.EqualEqual:
-				return isEqual(l, r), nil
-
 			case token.Minus:
 				return lparsed - rparsed, err
 			case token.Slash:
@@ -387,7 +795,21 @@ func (*Evaluator) Binary(op token.Token, left, right ExpEvaluator) ExpEvaluator
 			}
 		}
 
-		return nil, fmt.Errorf("Unexpected token %s", op.Lexeme)
+		return nil, e.error(op, "unexpected operator %s", op.Lexeme)
+	})
+}
+
+func (e *Evaluator) Lambda(keyword token.Token, params []token.Token, body []StmtEvaluator) ExpEvaluator {
+	name := keyword
+	name.Kind, name.Lexeme = token.Identifier, "lambda"
+	decl := &funcDecl{e, name, params, body}
+
+	return expEvalFunc(func() (any, error) {
+		if err := e.allocate(); err != nil {
+			return nil, err
+		}
+
+		return decl.function(e.environment), nil
 	})
 }
 
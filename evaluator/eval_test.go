@@ -1 +1,427 @@
 package eval
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/havrydotdev/golox/lox"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/resolver"
+	"github.com/havrydotdev/golox/scanner"
+)
+
+func run(t *testing.T, source string) *Evaluator {
+	t.Helper()
+
+	tokens, errs := scanner.New(source).Scan()
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	e := New().(*Evaluator)
+	res := resolver.New[ExpEvaluator, StmtEvaluator](e)
+	nodes, errs := parser.New(tokens, res).Parse()
+	for _, err := range errs {
+		t.Fatal(err)
+	}
+
+	stmts, errs := res.Resolve(nodes)
+	for _, err := range errs {
+		t.Fatal(err)
+	}
+
+	for _, stmt := range stmts {
+		if err := stmt.Eval(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return e
+}
+
+func expectGlobal(t *testing.T, e *Evaluator, name string, expected any) {
+	t.Helper()
+
+	val, ok := e.globals.Get(name)
+	if !ok {
+		t.Fatalf("global %s is not defined", name)
+	}
+
+	if val != expected {
+		t.Errorf("expected %s to be %v, got %v", name, expected, val)
+	}
+}
+
+func TestMethods(t *testing.T) {
+	e := run(t, `
+class Counter {
+  add(n) {
+    this.count = this.count + n;
+    return this;
+  }
+}
+
+var c = Counter();
+c.count = 1;
+var add = c.add;
+add(2).add(3);
+var result = c.count;
+`)
+
+	expectGlobal(t, e, "result", float32(6))
+}
+
+func TestReturnFromNestedBlock(t *testing.T) {
+	e := run(t, `
+fun f(x) {
+  if (x) {
+    return "then";
+  }
+
+  return "after";
+}
+
+var a = f(true);
+var b = f(false);
+`)
+
+	expectGlobal(t, e, "a", "then")
+	expectGlobal(t, e, "b", "after")
+}
+
+func TestInheritance(t *testing.T) {
+	e := run(t, `
+class A {
+  name() { return "A"; }
+  greet() { return "hello from " + this.name(); }
+}
+
+class B < A {
+  name() { return "B"; }
+  greet() { return super.greet() + " via " + super.name(); }
+}
+
+var inherited = B().greet();
+`)
+
+	expectGlobal(t, e, "inherited", "hello from B via A")
+}
+
+func TestInheritanceErrors(t *testing.T) {
+	tests := map[string]string{
+		"not a class": "var NotAClass = 1; class A < NotAClass {}",
+		"itself":      "class A {} class A < A {}",
+	}
+
+	for name, source := range tests {
+		t.Run(name, func(t *testing.T) {
+			tokens, errs := scanner.New(source).Scan()
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+
+			stmts, errs := parser.New(tokens, New()).Parse()
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+
+			var err error
+			for _, stmt := range stmts {
+				err = stmt.Eval()
+			}
+
+			if err == nil {
+				t.Error("expected runtime error")
+			}
+		})
+	}
+}
+
+func TestInitializer(t *testing.T) {
+	e := run(t, `
+class Point {
+  init(x, y) {
+    this.x = x;
+    this.y = y;
+    if (x > 100) return;
+  }
+}
+
+class Point3 < Point {
+  init(x, y, z) {
+    super.init(x, y);
+    this.z = z;
+  }
+}
+
+var p = Point3(1, 2, 3);
+var sum = p.x + p.y + p.z;
+var again = p.init(4, 5, 6).x;
+`)
+
+	expectGlobal(t, e, "sum", float32(6))
+	expectGlobal(t, e, "again", float32(4))
+}
+
+func TestInitializerReturnValue(t *testing.T) {
+	tokens, errs := scanner.New("class A { init() { return 1; } }").Scan()
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	_, errs = parser.New(tokens, New()).Parse()
+	if len(errs) != 1 {
+		t.Errorf("expected 1 error, got %v", errs)
+	}
+}
+
+func TestClosureBinding(t *testing.T) {
+	e := run(t, `
+var a = "global";
+var first;
+var second;
+{
+  fun showA() {
+    return a;
+  }
+
+  first = showA();
+  var a = "block";
+  second = showA();
+}
+`)
+
+	expectGlobal(t, e, "first", "global")
+	expectGlobal(t, e, "second", "global")
+}
+
+func TestBreakContinue(t *testing.T) {
+	e := run(t, `
+var sum = 0;
+for (var i = 0; i < 10; i = i + 1) {
+  var skip = i == 3;
+  if (skip) continue;
+  if (i == 6) break;
+  sum = sum + i;
+}
+
+var loops = 0;
+while (true) {
+  {
+    loops = loops + 1;
+    if (loops < 4) continue;
+  }
+
+  break;
+}
+`)
+
+	expectGlobal(t, e, "sum", float32(0+1+2+4+5))
+	expectGlobal(t, e, "loops", float32(4))
+}
+
+func TestLists(t *testing.T) {
+	e := run(t, `
+var xs = [1, 2, [3]];
+xs[0] = -xs[0];
+xs[2][0] = 4;
+push(xs, 5);
+var popped = pop(xs);
+var length = len(xs);
+var first = xs[0];
+var nested = xs[2][0];
+`)
+
+	expectGlobal(t, e, "popped", float32(5))
+	expectGlobal(t, e, "length", float32(3))
+	expectGlobal(t, e, "first", float32(-1))
+	expectGlobal(t, e, "nested", float32(4))
+}
+
+func TestMaps(t *testing.T) {
+	e := run(t, `
+class A {}
+var a = A();
+var m = {"one": 1, 2: "two", nil: false};
+m["one"] = m["one"] + 10;
+m[a] = "instance";
+m[print] = "native";
+var removed = delete(m, 2);
+var missing = delete(m, 2);
+var length = len(m);
+var one = m["one"];
+var byIdentity = m[a];
+var native = m[print];
+var hasNil = has(m, nil);
+var hasOther = has(m, A());
+var firstKey = keys(m)[0];
+var lastValue = values(m)[len(m) - 1];
+var same = print == print;
+`)
+
+	expectGlobal(t, e, "removed", true)
+	expectGlobal(t, e, "missing", false)
+	expectGlobal(t, e, "length", float32(4))
+	expectGlobal(t, e, "one", float32(11))
+	expectGlobal(t, e, "byIdentity", "instance")
+	expectGlobal(t, e, "native", "native")
+	expectGlobal(t, e, "hasNil", true)
+	expectGlobal(t, e, "hasOther", false)
+	expectGlobal(t, e, "firstKey", "one")
+	expectGlobal(t, e, "lastValue", "native")
+	expectGlobal(t, e, "same", true)
+}
+
+func TestLambdas(t *testing.T) {
+	e := run(t, `
+fun apply(f, x) { return f(x); }
+
+var count = 0;
+fun counter() {
+  var n = 0;
+  return fun () { n = n + 1; return n; };
+}
+
+var next = counter();
+next();
+var second = next();
+var squared = apply(fun (x) { return x * x; }, 3);
+fun () { count = count + 1; }();
+`)
+
+	expectGlobal(t, e, "second", float32(2))
+	expectGlobal(t, e, "squared", float32(9))
+	expectGlobal(t, e, "count", float32(1))
+}
+
+func TestAssignmentTargets(t *testing.T) {
+	tests := map[string]bool{
+		"a = 1;":          true,
+		"a.b = 1;":        true,
+		"a[0] = 1;":       true,
+		"a.b[0].c = 1;":   true,
+		"a = b = 1;":      true,
+		"(a) = 1;":        false,
+		"1 + a = 1;":      false,
+		"a + b.c = 1;":    false,
+		"-a[0] = 1;":      false,
+		"f() = 1;":        false,
+		"a.b(c) = 1;":     false,
+		"[1, a][0] = 1;":  true,
+		"a[b = 1] = 2;":   true,
+		"a[0] + 1 = 2;":   false,
+		"this = 1;":       false,
+		"a == b = 1;":     false,
+		"!a = 1;":         false,
+		"a or b = 1;":     false,
+		"a = b or c = 1;": false,
+	}
+
+	for source, valid := range tests {
+		t.Run(source, func(t *testing.T) {
+			tokens, errs := scanner.New(source).Scan()
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+
+			_, errs = parser.New(tokens, New()).Parse()
+			if valid && len(errs) != 0 {
+				t.Errorf("expected no errors, got %v", errs)
+			} else if !valid && len(errs) == 0 {
+				t.Error("expected invalid assignment target")
+			}
+		})
+	}
+}
+
+func TestListErrors(t *testing.T) {
+	tests := []string{
+		"var a = [1]; a[-1];",
+		"var a = [1]; a[1];",
+		"var a = [1]; a[0.5];",
+		"var a = [1]; a[\"0\"] = 1;",
+		"var a = 1; a[0];",
+		"pop([]);",
+		"var m = {}; m[\"a\"];",
+		"var m = {}; m[[]] = 1;",
+		"var m = {}; m[{}] = 1;",
+		"var m = {[1]: 1};",
+		"has([], 1);",
+		"keys(nil);",
+	}
+
+	for _, source := range tests {
+		t.Run(source, func(t *testing.T) {
+			tokens, errs := scanner.New(source).Scan()
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+
+			stmts, errs := parser.New(tokens, New()).Parse()
+			if len(errs) != 0 {
+				t.Fatal(errs)
+			}
+
+			var err error
+			for _, stmt := range stmts {
+				err = stmt.Eval()
+			}
+
+			if err == nil {
+				t.Error("expected runtime error")
+			}
+		})
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	source := `
+fun inner() {
+  return 1 + nil;
+}
+
+fun outer() {
+  return inner();
+}
+
+outer();
+`
+	tokens, errs := scanner.NewFile("trace.lox", source).Scan()
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	e := New().(*Evaluator)
+	stmts, errs := parser.New(tokens, e).Parse()
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	var err error
+	for _, stmt := range stmts {
+		err = stmt.Eval()
+	}
+
+	var rerr *RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected runtime error, got %v", err)
+	}
+
+	if rerr.Token.Lexeme != "+" {
+		t.Errorf("expected error at '+', got %q", rerr.Token.Lexeme)
+	}
+
+	expected := []lox.Frame{
+		{Function: "inner", File: "trace.lox", Line: 3},
+		{Function: "outer", File: "trace.lox", Line: 7},
+		{Function: "script", File: "trace.lox", Line: 10},
+	}
+	if !reflect.DeepEqual(rerr.Frames, expected) {
+		t.Errorf("expected frames %v, got %v", expected, rerr.Frames)
+	}
+
+	if len(e.calls) != 0 {
+		t.Errorf("calls were not unwound: %v", e.calls)
+	}
+}
@@ -1,19 +1,50 @@
 package eval
 
+import "sort"
+
+// Object is a value with properties which isn't an instance of
+// a lox class, hosts implement it to expose their own values
+type Object interface {
+	Get(name string) (any, bool)
+	Set(name string, value any) error
+}
+
 type Instance struct {
-	Class
+	Class  *Class
 	fields map[string]any
 }
 
-func (i Instance) String() string {
-	return i.Name + " instance"
+func (i *Instance) String() string {
+	return i.Class.Name + " instance"
 }
 
-func (i Instance) Get(key string) (any, bool) {
+// Get looks up a field first, falling back to a method
+// of the instance's class bound to the instance
+func (i *Instance) Get(key string) (any, bool) {
 	val, ok := i.fields[key]
-	return val, ok
+	if ok {
+		return val, true
+	}
+
+	method, ok := i.Class.FindMethod(key)
+	if ok {
+		return method.Bind(i), true
+	}
+
+	return nil, false
 }
 
-func (i Instance) Set(key string, value any) {
+func (i *Instance) Set(key string, value any) {
 	i.fields[key] = value
 }
+
+// Fields are the sorted names of the fields set on the instance
+func (i *Instance) Fields() []string {
+	names := make([]string, 0, len(i.fields))
+	for name := range i.fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,111 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+)
+
+// maxDepth bounds the calls when there is no limit,
+// deeper recursion would overflow the Go stack
+const maxDepth = 10000
+
+// Limits bound what a program may use, zero is no limit, except
+// for Depth which is then maxDepth. Statements counts loop iterations
+// too and Allocations the lists, maps, instances, functions and
+// strings the program creates
+type Limits struct {
+	Statements  int
+	Depth       int
+	Allocations int
+}
+
+// usage is what the program used since the limits were set
+type usage struct {
+	statements  int
+	allocations int
+}
+
+type StatementLimitError struct {
+	Limit int
+}
+
+func (e *StatementLimitError) Error() string {
+	return fmt.Sprintf("exceeded the limit of %d statements", e.Limit)
+}
+
+type DepthLimitError struct {
+	Limit int
+}
+
+func (e *DepthLimitError) Error() string {
+	return fmt.Sprintf("exceeded the limit of %d nested calls", e.Limit)
+}
+
+type AllocationLimitError struct {
+	Limit int
+}
+
+func (e *AllocationLimitError) Error() string {
+	return fmt.Sprintf("exceeded the limit of %d allocated values", e.Limit)
+}
+
+// SetContext makes the program unwind with an Interrupt once the
+// context is done, it is checked at every loop iteration and call
+func (e *Evaluator) SetContext(ctx context.Context) {
+	e.done, e.ctx = nil, ctx
+	if ctx != nil {
+		e.done = ctx.Done()
+	}
+}
+
+// SetLimits sets the limits and forgets what was used so far
+func (e *Evaluator) SetLimits(limits Limits) {
+	e.limits = limits
+	e.usage = usage{}
+}
+
+// interrupted checks the context, it is cheap when there is none
+func (e *Evaluator) interrupted() error {
+	select {
+	case <-e.done:
+		return &Interrupt{e.ctx.Err()}
+	default:
+		return nil
+	}
+}
+
+// iterate is called at the start of every loop iteration, which
+// counts as a statement so even empty loops reach the limit
+func (e *Evaluator) iterate() error {
+	if err := e.interrupted(); err != nil {
+		return err
+	}
+
+	return e.countStatement()
+}
+
+func (e *Evaluator) countStatement() error {
+	e.usage.statements++
+	if limit := e.limits.Statements; limit != 0 && e.usage.statements > limit {
+		return &StatementLimitError{limit}
+	}
+
+	return nil
+}
+
+func (e *Evaluator) allocate() error {
+	e.usage.allocations++
+	if limit := e.limits.Allocations; limit != 0 && e.usage.allocations > limit {
+		return &AllocationLimitError{limit}
+	}
+
+	return nil
+}
+
+func (e *Evaluator) depthLimit() int {
+	if e.limits.Depth != 0 {
+		return e.limits.Depth
+	}
+
+	return maxDepth
+}
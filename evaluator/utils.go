@@ -15,6 +15,8 @@ func isTruthy(value any) bool {
 	return true
 }
 
+// isEqual compares values without panicking, every lox value is
+// either comparable by value or a pointer compared by identity
 func isEqual(left, right any) bool {
 	if left == nil && right == nil {
 		return true
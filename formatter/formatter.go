@@ -0,0 +1,425 @@
+// Package formatter reprints lox code with canonical
+// indentation, spacing and brace style, keeping its comments
+package formatter
+
+import (
+	"github.com/havrydotdev/golox/token"
+)
+
+// Doc is both the expression and the statement type of the
+// formatter algebra, print writes it when it is called
+type Doc struct {
+	print func()
+	// bodies which are blocks start on the line of their statement
+	block bool
+	// set for function declarations, classes print them as methods
+	function *function
+}
+
+type function struct {
+	params []token.Token
+	body   []*Doc
+}
+
+func (d *Doc) run() {
+	if d != nil && d.print != nil {
+		d.print()
+	}
+}
+
+type Formatter struct {
+	p *printer
+}
+
+// New creates a formatter of the source which was scanned into
+// the tokens and the comments, see scanner.Scanner.KeepComments
+func New(tokens []token.Token, comments []token.Token) *Formatter {
+	return &Formatter{&printer{tokens: tokens, comments: comments}}
+}
+
+// Format prints the statements parsed from the tokens of the formatter,
+// it fails if they don't cover the whole source
+func (f *Formatter) Format(stmts []*Doc) (string, error) {
+	for _, stmt := range stmts {
+		f.p.newline = true
+		stmt.run()
+	}
+
+	return f.p.finish()
+}
+
+func (f *Formatter) Grouping(expr *Doc) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.LeftParen)
+		expr.run()
+		f.p.token(token.RightParen)
+	}}
+}
+
+func (f *Formatter) Literal(value any) *Doc {
+	return &Doc{print: f.p.literal}
+}
+
+func (f *Formatter) This(keyword token.Token) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.This)
+	}}
+}
+
+func (f *Formatter) Super(keyword token.Token, method token.Token) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.Super)
+		f.p.token(token.Dot)
+		f.p.token(token.Identifier)
+	}}
+}
+
+func (f *Formatter) Variable(name token.Token) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.Identifier)
+	}}
+}
+
+func (f *Formatter) Get(name token.Token, expr *Doc) *Doc {
+	return &Doc{print: func() {
+		expr.run()
+		f.p.token(token.Dot)
+		f.p.token(token.Identifier)
+	}}
+}
+
+func (f *Formatter) Unary(op token.Token, right *Doc) *Doc {
+	return &Doc{print: func() {
+		f.p.token(op.Kind)
+		right.run()
+	}}
+}
+
+func (f *Formatter) Assign(name token.Token, value *Doc) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.Identifier)
+		f.assignValue(value)
+	}}
+}
+
+func (f *Formatter) Binary(op token.Token, left, right *Doc) *Doc {
+	return f.infix(op, left, right)
+}
+
+func (f *Formatter) Logical(op token.Token, left, right *Doc) *Doc {
+	return f.infix(op, left, right)
+}
+
+func (f *Formatter) Call(callee *Doc, paren token.Token, args []*Doc) *Doc {
+	return &Doc{print: func() {
+		callee.run()
+		f.p.token(token.LeftParen)
+		f.list(args)
+		f.p.token(token.RightParen)
+	}}
+}
+
+func (f *Formatter) Set(object *Doc, name token.Token, value *Doc) *Doc {
+	return &Doc{print: func() {
+		object.run()
+		f.p.token(token.Dot)
+		f.p.token(token.Identifier)
+		f.assignValue(value)
+	}}
+}
+
+func (f *Formatter) List(bracket token.Token, elements []*Doc) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.LeftBracket)
+		f.list(elements)
+		f.p.token(token.RightBracket)
+	}}
+}
+
+func (f *Formatter) Map(brace token.Token, keys []*Doc, values []*Doc) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.LeftBrace)
+		for i := range keys {
+			if i != 0 {
+				f.p.token(token.Comma)
+				f.p.space = true
+			}
+
+			keys[i].run()
+			f.p.token(token.Colon)
+			f.p.space = true
+			values[i].run()
+		}
+		f.p.token(token.RightBrace)
+	}}
+}
+
+func (f *Formatter) Index(object *Doc, bracket token.Token, index *Doc) *Doc {
+	return &Doc{print: func() {
+		object.run()
+		f.p.token(token.LeftBracket)
+		index.run()
+		f.p.token(token.RightBracket)
+	}}
+}
+
+func (f *Formatter) SetIndex(object *Doc, bracket token.Token, index *Doc, value *Doc) *Doc {
+	return &Doc{print: func() {
+		object.run()
+		f.p.token(token.LeftBracket)
+		index.run()
+		f.p.token(token.RightBracket)
+		f.assignValue(value)
+	}}
+}
+
+func (f *Formatter) Lambda(keyword token.Token, params []token.Token, body []*Doc) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.Fun)
+		f.p.space = true
+		f.params(params)
+		f.p.space = true
+		f.block(body)
+	}}
+}
+
+func (f *Formatter) Block(stmts []*Doc) *Doc {
+	return &Doc{block: true, print: func() {
+		f.block(stmts)
+	}}
+}
+
+func (f *Formatter) While(cond *Doc, body *Doc) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.While)
+		f.p.space = true
+		f.p.token(token.LeftParen)
+		cond.run()
+		f.p.token(token.RightParen)
+		f.p.space = true
+		body.run()
+	}}
+}
+
+func (f *Formatter) For(init *Doc, cond *Doc, incr *Doc, body *Doc) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.For)
+		f.p.space = true
+		f.p.token(token.LeftParen)
+
+		// init prints its own ';'
+		if init != nil {
+			init.run()
+		} else {
+			f.p.token(token.Semicolon)
+		}
+
+		if cond != nil {
+			f.p.space = true
+			cond.run()
+		}
+		f.p.token(token.Semicolon)
+
+		if incr != nil {
+			f.p.space = true
+			incr.run()
+		}
+		f.p.token(token.RightParen)
+
+		f.p.space = true
+		body.run()
+	}}
+}
+
+func (f *Formatter) Break(keyword token.Token) *Doc {
+	return f.keyword(token.Break)
+}
+
+func (f *Formatter) Continue(keyword token.Token) *Doc {
+	return f.keyword(token.Continue)
+}
+
+func (f *Formatter) ExprStatement(expr *Doc) *Doc {
+	return &Doc{print: func() {
+		expr.run()
+		f.p.token(token.Semicolon)
+	}}
+}
+
+func (f *Formatter) If(cond *Doc, then *Doc, _else *Doc) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.If)
+		f.p.space = true
+		f.p.token(token.LeftParen)
+		cond.run()
+		f.p.token(token.RightParen)
+		f.p.space = true
+		then.run()
+
+		if _else != nil {
+			// "} else {" but a statement which isn't
+			// a block is followed by else on a new line
+			if then.block {
+				f.p.space = true
+			} else {
+				f.p.newline = true
+			}
+
+			f.p.token(token.Else)
+			f.p.space = true
+			_else.run()
+		}
+	}}
+}
+
+func (f *Formatter) Var(name token.Token, init *Doc) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.Var)
+		f.p.space = true
+		f.p.token(token.Identifier)
+		if init != nil {
+			f.assignValue(init)
+		}
+		f.p.token(token.Semicolon)
+	}}
+}
+
+func (f *Formatter) Return(keyword token.Token, value *Doc) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.Return)
+		if value != nil {
+			f.p.space = true
+			value.run()
+		}
+		f.p.token(token.Semicolon)
+	}}
+}
+
+func (f *Formatter) Class(name token.Token, superclass *Doc, methods []*Doc) *Doc {
+	return &Doc{print: func() {
+		f.p.token(token.Class)
+		f.p.space = true
+		f.p.token(token.Identifier)
+
+		if superclass != nil {
+			f.p.space = true
+			f.p.token(token.Less)
+			f.p.space = true
+			superclass.run()
+		}
+
+		f.p.space = true
+		f.p.token(token.LeftBrace)
+		f.p.indent++
+		for _, method := range methods {
+			f.p.newline = true
+			if method.function != nil {
+				f.function(method.function)
+			}
+		}
+		f.closeBlock(len(methods) != 0)
+	}}
+}
+
+func (f *Formatter) Function(name token.Token, params []token.Token, body []*Doc) *Doc {
+	decl := &function{params, body}
+
+	return &Doc{function: decl, print: func() {
+		f.p.token(token.Fun)
+		f.p.space = true
+		f.function(decl)
+	}}
+}
+
+func (f *Formatter) NilExpr() *Doc {
+	return &Doc{}
+}
+
+func (f *Formatter) NilStmt() *Doc {
+	return &Doc{}
+}
+
+// function prints the name, the parameters and the body,
+// which is the declaration of a method
+func (f *Formatter) function(decl *function) {
+	f.p.token(token.Identifier)
+	f.params(decl.params)
+	f.p.space = true
+	f.block(decl.body)
+}
+
+func (f *Formatter) params(params []token.Token) {
+	f.p.token(token.LeftParen)
+	for i := range params {
+		if i != 0 {
+			f.p.token(token.Comma)
+			f.p.space = true
+		}
+
+		f.p.token(token.Identifier)
+	}
+	f.p.token(token.RightParen)
+}
+
+func (f *Formatter) block(stmts []*Doc) {
+	f.p.token(token.LeftBrace)
+	f.p.indent++
+	for _, stmt := range stmts {
+		f.p.newline = true
+		stmt.run()
+	}
+	f.closeBlock(len(stmts) != 0)
+}
+
+// closeBlock prints the '}' of a block whose '{' was printed,
+// an empty block stays on one line unless it has comments
+func (f *Formatter) closeBlock(multiline bool) {
+	// comments at the end of the block are indented like its statements
+	comment := f.p.comment
+	f.p.flush()
+	f.p.indent--
+	f.p.continued = false
+
+	if multiline {
+		f.p.newline = true
+	} else if f.p.comment != comment {
+		f.p.space = true
+	}
+
+	f.p.token(token.RightBrace)
+}
+
+func (f *Formatter) list(elements []*Doc) {
+	for i, element := range elements {
+		if i != 0 {
+			f.p.token(token.Comma)
+			f.p.space = true
+		}
+
+		element.run()
+	}
+}
+
+func (f *Formatter) infix(op token.Token, left, right *Doc) *Doc {
+	return &Doc{print: func() {
+		left.run()
+		f.p.space = true
+		f.p.token(op.Kind)
+		f.p.space = true
+		right.run()
+	}}
+}
+
+func (f *Formatter) assignValue(value *Doc) {
+	f.p.space = true
+	f.p.token(token.Equal)
+	f.p.space = true
+	value.run()
+}
+
+func (f *Formatter) keyword(kind token.Kind) *Doc {
+	return &Doc{print: func() {
+		f.p.token(kind)
+		f.p.token(token.Semicolon)
+	}}
+}
@@ -0,0 +1,102 @@
+package formatter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/scanner"
+)
+
+func format(t *testing.T, source string) string {
+	t.Helper()
+
+	scan := scanner.New(source).KeepComments()
+	tokens, errs := scan.Scan()
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	f := New(tokens, scan.Comments())
+	stmts, errs := parser.New[*Doc, *Doc](tokens, f).Parse()
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	formatted, err := f.Format(stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return formatted
+}
+
+func TestFormat(t *testing.T) {
+	source := `// header
+
+var a=1;// trailing
+class A<B{
+
+
+  m(x,y){return x+ // mid
+  y;}
+  // end of class
+}
+fun f(){
+  /* only comment */
+}
+var m = {"a":1,  "b" : [1,2]};
+for(;;){break;}
+if(a)print(a);else if(b){print(b);}else print(c);
+var l = fun(){};`
+
+	expected := `// header
+
+var a = 1; // trailing
+class A < B {
+  m(x, y) {
+    return x + // mid
+      y;
+  }
+  // end of class
+}
+fun f() {
+  /* only comment */
+}
+var m = {"a": 1, "b": [1, 2]};
+for (;;) {
+  break;
+}
+if (a) print(a);
+else if (b) {
+  print(b);
+} else print(c);
+var l = fun () {};
+`
+
+	if formatted := format(t, source); formatted != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, formatted)
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	files, err := filepath.Glob("../_examples/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			source, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			once := format(t, string(source))
+			if twice := format(t, once); twice != once {
+				t.Errorf("formatting again changed\n%s\ninto\n%s", once, twice)
+			}
+		})
+	}
+}
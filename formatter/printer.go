@@ -0,0 +1,157 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/havrydotdev/golox/token"
+)
+
+const indentation = "  "
+
+// printer writes the tokens of the source in the order they
+// were scanned, the formatter only decides what goes between
+// them, so every comment can be put back between the same
+// two tokens it was found between
+type printer struct {
+	tokens   []token.Token
+	comments []token.Token
+	// indexes of the next token and comment to print
+	next    int
+	comment int
+
+	out    strings.Builder
+	indent int
+
+	// what the formatter wants before the next token
+	newline bool
+	space   bool
+	// set when a comment broke a line the formatter
+	// didn't break, the next line is indented further
+	continued bool
+
+	started bool
+	// last line of the source which was printed
+	line int
+	last token.Kind
+
+	err error
+}
+
+// token prints the next token of the source, which must be of the kind
+func (p *printer) token(kind token.Kind) {
+	if p.err != nil {
+		return
+	}
+
+	if p.next >= len(p.tokens) || p.tokens[p.next].Kind != kind {
+		p.err = fmt.Errorf("internal error: formatter lost track of the tokens at token %d", p.next)
+		return
+	}
+
+	p.print(p.tokens[p.next])
+	p.next++
+}
+
+// literal prints the next token, which must be a literal,
+// its lexeme is kept as it was written
+func (p *printer) literal() {
+	if p.next < len(p.tokens) {
+		switch kind := p.tokens[p.next].Kind; kind {
+		case token.Number, token.String, token.True, token.False, token.Nil:
+			p.token(kind)
+			return
+		}
+	}
+
+	p.token(token.Number)
+}
+
+func (p *printer) print(tok token.Token) {
+	p.printComments(tok)
+	p.separate(tok)
+
+	p.out.WriteString(tok.Lexeme)
+	p.started = true
+	p.line = tok.Line + strings.Count(tok.Lexeme, "\n")
+	p.last = tok.Kind
+}
+
+// printComments prints the comments which come before the token,
+// the ones which follow code on its line are kept there
+func (p *printer) printComments(before token.Token) {
+	wanted := p.newline
+	for p.comment < len(p.comments) && p.comments[p.comment].Start < before.Start {
+		comment := p.comments[p.comment]
+		p.comment++
+
+		trailing := p.started && comment.Line == p.line
+		if trailing {
+			p.out.WriteString(" " + comment.Lexeme)
+		} else {
+			p.newline = true
+			p.separate(comment)
+			p.out.WriteString(comment.Lexeme)
+		}
+
+		p.started = true
+		p.line = comment.Line + strings.Count(comment.Lexeme, "\n")
+		p.last = token.Comment
+
+		// the rest of the line belongs to a line comment
+		if !trailing || strings.HasPrefix(comment.Lexeme, "//") {
+			p.newline = true
+		}
+	}
+
+	p.continued = p.newline && !wanted
+}
+
+// flush prints the comments which come before the next token
+func (p *printer) flush() {
+	if p.next < len(p.tokens) {
+		p.printComments(p.tokens[p.next])
+	}
+}
+
+func (p *printer) separate(next token.Token) {
+	switch {
+	case !p.started:
+	case p.newline:
+		p.out.WriteString("\n")
+
+		// a blank line of the source is kept, unless
+		// it is at the start or at the end of a block
+		if next.Line > p.line+1 && p.last != token.LeftBrace && next.Kind != token.RightBrace {
+			p.out.WriteString("\n")
+		}
+
+		indent := p.indent
+		if p.continued {
+			indent++
+		}
+		p.out.WriteString(strings.Repeat(indentation, indent))
+	case p.space:
+		p.out.WriteString(" ")
+	}
+
+	p.newline, p.space, p.continued = false, false, false
+}
+
+// finish prints the comments at the end of the source
+func (p *printer) finish() (string, error) {
+	if p.err == nil && (p.next >= len(p.tokens) || p.tokens[p.next].Kind != token.Eof) {
+		p.err = fmt.Errorf("internal error: formatter stopped at token %d", p.next)
+	}
+
+	if p.err != nil {
+		return "", p.err
+	}
+
+	p.printComments(p.tokens[p.next])
+	if p.started {
+		p.out.WriteString("\n")
+	}
+
+	return p.out.String(), nil
+}
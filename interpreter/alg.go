@@ -10,6 +10,8 @@ import "github.com/havrydotdev/golox/token"
 type Alg[E any, S any] interface {
 	Grouping(expr E) E
 	Literal(value any) E
+	This(keyword token.Token) E
+	Super(keyword token.Token, method token.Token) E
 	Variable(name token.Token) E
 	Get(name token.Token, expr E) E
 	Unary(op token.Token, right E) E
@@ -18,14 +20,26 @@ type Alg[E any, S any] interface {
 	Logical(op token.Token, left, right E) E
 	Call(callee E, paren token.Token, args []E) E
 	Set(object E, name token.Token, value E) E
+	List(bracket token.Token, elements []E) E
+	// keys[i] is the key of values[i]
+	Map(brace token.Token, keys []E, values []E) E
+	Index(object E, bracket token.Token, index E) E
+	SetIndex(object E, bracket token.Token, index E, value E) E
+	// Lambda is an anonymous function, keyword is its "fun" token
+	Lambda(keyword token.Token, params []token.Token, body []S) E
 
 	Block(stmts []S) S
 	While(cond E, body S) S
+	// init, cond and incr are zero values when omitted
+	For(init S, cond E, incr E, body S) S
+	Break(keyword token.Token) S
+	Continue(keyword token.Token) S
 	ExprStatement(expr E) S
 	If(cond E, then S, _else S) S
+	// init is a zero value when omitted
 	Var(name token.Token, init E) S
 	Return(keyword token.Token, value E) S
-	Class(name token.Token, methods []S) S
+	Class(name token.Token, superclass E, methods []S) S
 	Function(name token.Token, params []token.Token, body []S) S
 
 	NilExpr() E
@@ -0,0 +1,132 @@
+package interp
+
+import "github.com/havrydotdev/golox/token"
+
+// Nop is an algebra which builds nothing, parsing with it
+// (or with a resolver wrapping it) checks a program
+// without running any of it
+type Nop struct{}
+
+type nothing = struct{}
+
+func (Nop) Grouping(expr nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Literal(value any) nothing {
+	return nothing{}
+}
+
+func (Nop) This(keyword token.Token) nothing {
+	return nothing{}
+}
+
+func (Nop) Super(keyword token.Token, method token.Token) nothing {
+	return nothing{}
+}
+
+func (Nop) Variable(name token.Token) nothing {
+	return nothing{}
+}
+
+func (Nop) Get(name token.Token, expr nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Unary(op token.Token, right nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Assign(name token.Token, value nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Binary(op token.Token, left, right nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Logical(op token.Token, left, right nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Call(callee nothing, paren token.Token, args []nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Set(object nothing, name token.Token, value nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) List(bracket token.Token, elements []nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Map(brace token.Token, keys []nothing, values []nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Index(object nothing, bracket token.Token, index nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) SetIndex(object nothing, bracket token.Token, index nothing, value nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Lambda(keyword token.Token, params []token.Token, body []nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Block(stmts []nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) While(cond nothing, body nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) For(init nothing, cond nothing, incr nothing, body nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Break(keyword token.Token) nothing {
+	return nothing{}
+}
+
+func (Nop) Continue(keyword token.Token) nothing {
+	return nothing{}
+}
+
+func (Nop) ExprStatement(expr nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) If(cond nothing, then nothing, _else nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Var(name token.Token, init nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Return(keyword token.Token, value nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Class(name token.Token, superclass nothing, methods []nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) Function(name token.Token, params []token.Token, body []nothing) nothing {
+	return nothing{}
+}
+
+func (Nop) NilExpr() nothing {
+	return nothing{}
+}
+
+func (Nop) NilStmt() nothing {
+	return nothing{}
+}
+
+var _ Alg[struct{}, struct{}] = Nop{}
@@ -0,0 +1,14 @@
+package interp
+
+import "github.com/havrydotdev/golox/token"
+
+// Positioned is implemented by algebras which want to know where
+// the statements start, e.g. to stop at them in a debugger
+//
+// the parser calls At for every statement of a program or a body
+// and for the bodies of if, while and for, but not for blocks
+// (their statements are located instead) or class methods,
+// the returned statement replaces the given one
+type Positioned[S any] interface {
+	At(start token.Token, stmt S) S
+}
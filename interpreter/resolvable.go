@@ -0,0 +1,11 @@
+package interp
+
+// Resolvable is implemented by algebras which can make use
+// of the scope depth computed by the resolver for variables,
+// assignments, "this" and "super"
+//
+// depth is the number of scopes between the expression
+// and the one where the name is declared
+type Resolvable[E any] interface {
+	Resolve(expr E, depth int)
+}
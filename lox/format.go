@@ -0,0 +1,53 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// maxInteger is where whole numbers start
+// to be printed with an exponent
+const maxInteger = 1e21
+
+// Stringify formats the value the way print, the REPL and str do,
+// whole numbers have no fraction, the others are the shortest
+// form which reads back as the same number
+func Stringify(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "nil"
+	case float32:
+		return formatNumber(value)
+	case string:
+		return value
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// stringifyNested formats the elements of lists and maps, strings
+// are quoted there so that "2" and 2 can be told apart
+func stringifyNested(value any) string {
+	if value, ok := value.(string); ok {
+		return `"` + value + `"`
+	}
+
+	return Stringify(value)
+}
+
+func formatNumber(n float32) string {
+	f := float64(n)
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case f == math.Trunc(f) && math.Abs(f) < maxInteger:
+		return strconv.FormatFloat(f, 'f', -1, 32)
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 32)
+}
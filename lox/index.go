@@ -0,0 +1,27 @@
+package lox
+
+import "fmt"
+
+// Index reads the element of a list or the value of a map
+func Index(object any, index any) (any, error) {
+	switch object := object.(type) {
+	case *List:
+		return object.Get(index)
+	case *Map:
+		return object.Get(index)
+	}
+
+	return nil, fmt.Errorf("only lists and maps can be indexed, got %s", Stringify(object))
+}
+
+// SetIndex assigns the element of a list or the value of a map
+func SetIndex(object any, index any, value any) error {
+	switch object := object.(type) {
+	case *List:
+		return object.Set(index, value)
+	case *Map:
+		return object.Set(index, value)
+	}
+
+	return fmt.Errorf("only lists and maps can be indexed, got %s", Stringify(object))
+}
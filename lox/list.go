@@ -0,0 +1,82 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// List is the list value shared by the backends,
+// it is a pointer so that lists are passed by reference
+type List struct {
+	Elements []any
+}
+
+func NewList(elements []any) *List {
+	return &List{elements}
+}
+
+func (l *List) Get(index any) (any, error) {
+	i, err := l.index(index)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.Elements[i], nil
+}
+
+func (l *List) Set(index any, value any) error {
+	i, err := l.index(index)
+	if err != nil {
+		return err
+	}
+
+	l.Elements[i] = value
+	return nil
+}
+
+func (l *List) Push(value any) {
+	l.Elements = append(l.Elements, value)
+}
+
+func (l *List) Pop() (any, error) {
+	if len(l.Elements) == 0 {
+		return nil, fmt.Errorf("can't pop from an empty list")
+	}
+
+	last := l.Elements[len(l.Elements)-1]
+	l.Elements = l.Elements[:len(l.Elements)-1]
+
+	return last, nil
+}
+
+func (l *List) String() string {
+	elements := make([]string, len(l.Elements))
+	for i, element := range l.Elements {
+		elements[i] = stringifyNested(element)
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// index checks that the value is a whole number within the list
+func (l *List) index(index any) (int, error) {
+	num, ok := index.(float32)
+	if !ok {
+		return 0, fmt.Errorf("list index must be a number, got %s", Stringify(index))
+	}
+
+	i := int(num)
+	if float32(i) != num {
+		return 0, fmt.Errorf("list index must be a whole number, got %s", Stringify(index))
+	}
+
+	if i < 0 {
+		return 0, fmt.Errorf("list index %d is negative", i)
+	}
+
+	if i >= len(l.Elements) {
+		return 0, fmt.Errorf("list index %d out of range for length %d", i, len(l.Elements))
+	}
+
+	return i, nil
+}
@@ -0,0 +1,134 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// Map is the hash map value shared by the backends,
+// its keys are kept in the order they were inserted
+//
+// nil, booleans, numbers and strings are keys by value,
+// classes, instances and functions are keys by identity,
+// lists and maps are mutable so they can't be keys
+type Map struct {
+	values map[any]any
+	order  []any
+}
+
+func NewMap() *Map {
+	return &Map{values: make(map[any]any)}
+}
+
+func (m *Map) Get(key any) (any, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+
+	val, ok := m.values[key]
+	if !ok {
+		return nil, fmt.Errorf("undefined key %s", Stringify(key))
+	}
+
+	return val, nil
+}
+
+func (m *Map) Set(key any, value any) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	if _, ok := m.values[key]; !ok {
+		m.order = append(m.order, key)
+	}
+
+	m.values[key] = value
+	return nil
+}
+
+func (m *Map) Has(key any) (bool, error) {
+	if err := checkKey(key); err != nil {
+		return false, err
+	}
+
+	_, ok := m.values[key]
+	return ok, nil
+}
+
+// Delete removes the key and reports whether it was there
+func (m *Map) Delete(key any) (bool, error) {
+	if err := checkKey(key); err != nil {
+		return false, err
+	}
+
+	if _, ok := m.values[key]; !ok {
+		return false, nil
+	}
+
+	delete(m.values, key)
+	for i, k := range m.order {
+		if k == key {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+
+	return true, nil
+}
+
+func (m *Map) Len() int {
+	return len(m.order)
+}
+
+func (m *Map) Keys() []any {
+	keys := make([]any, len(m.order))
+	copy(keys, m.order)
+
+	return keys
+}
+
+func (m *Map) Values() []any {
+	values := make([]any, len(m.order))
+	for i, key := range m.order {
+		values[i] = m.values[key]
+	}
+
+	return values
+}
+
+func (m *Map) String() string {
+	entries := make([]string, len(m.order))
+	for i, key := range m.order {
+		entries[i] = stringifyNested(key) + ": " + stringifyNested(m.values[key])
+	}
+
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// checkKey makes sure the value can be used as a key,
+// go map operations panic on keys which are not comparable
+func checkKey(key any) error {
+	switch key := key.(type) {
+	case nil, bool, string:
+		return nil
+	case float32:
+		if math.IsNaN(float64(key)) {
+			return fmt.Errorf("NaN can't be used as a map key")
+		}
+
+		return nil
+	case *List, *Map:
+		return fmt.Errorf("%s can't be used as a map key", Stringify(key))
+	}
+
+	// everything else is an object which is compared by identity,
+	// objects of the host are structs holding their pointer
+	v := reflect.ValueOf(key)
+	if v.Kind() == reflect.Pointer || (v.Kind() == reflect.Struct && v.Comparable()) {
+		return nil
+	}
+
+	return fmt.Errorf("%s can't be used as a map key", Stringify(key))
+}
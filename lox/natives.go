@@ -0,0 +1,131 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Host is what natives can use of the backend running them
+type Host interface {
+	Stdin() io.Reader
+	Stdout() io.Writer
+}
+
+// Native describes a global which isn't declared by the scripts,
+// Params is nil for values which aren't functions. Call is nil
+// for the globals a host defines, see golox.Engine.Defined
+type Native struct {
+	Name   string
+	Params []string
+	Call   func(host Host, args []any) (any, error)
+}
+
+// Natives are the functions every backend defines, the
+// backends wrap Call with their own calling convention
+var Natives = []Native{
+	{"clock", []string{}, func(host Host, args []any) (any, error) {
+		return time.Now().Unix() / 1000, nil
+	}},
+	{"print", []string{"value"}, func(host Host, args []any) (any, error) {
+		_, err := fmt.Fprintln(host.Stdout(), Stringify(args[0]))
+		return nil, err
+	}},
+	{"len", []string{"value"}, func(host Host, args []any) (any, error) {
+		return Len(args[0])
+	}},
+	{"push", []string{"list", "value"}, func(host Host, args []any) (any, error) {
+		return Push(args[0], args[1])
+	}},
+	{"pop", []string{"list"}, func(host Host, args []any) (any, error) {
+		return Pop(args[0])
+	}},
+	{"has", []string{"map", "key"}, func(host Host, args []any) (any, error) {
+		return Has(args[0], args[1])
+	}},
+	{"delete", []string{"map", "key"}, func(host Host, args []any) (any, error) {
+		return Delete(args[0], args[1])
+	}},
+	{"keys", []string{"map"}, func(host Host, args []any) (any, error) {
+		return Keys(args[0])
+	}},
+	{"values", []string{"map"}, func(host Host, args []any) (any, error) {
+		return Values(args[0])
+	}},
+	{"str", []string{"value"}, func(host Host, args []any) (any, error) {
+		return Str(args[0])
+	}},
+}
+
+func Len(value any) (any, error) {
+	switch value := value.(type) {
+	case *List:
+		return float32(len(value.Elements)), nil
+	case *Map:
+		return float32(value.Len()), nil
+	case string:
+		return float32(len(value)), nil
+	}
+
+	return nil, fmt.Errorf("len expects a list, a map or a string, got %s", Stringify(value))
+}
+
+func Push(list any, value any) (any, error) {
+	l, ok := list.(*List)
+	if !ok {
+		return nil, fmt.Errorf("push expects a list, got %s", Stringify(list))
+	}
+
+	l.Push(value)
+	return nil, nil
+}
+
+func Pop(list any) (any, error) {
+	l, ok := list.(*List)
+	if !ok {
+		return nil, fmt.Errorf("pop expects a list, got %s", Stringify(list))
+	}
+
+	return l.Pop()
+}
+
+func Has(m any, key any) (any, error) {
+	hashMap, ok := m.(*Map)
+	if !ok {
+		return nil, fmt.Errorf("has expects a map, got %s", Stringify(m))
+	}
+
+	return hashMap.Has(key)
+}
+
+func Delete(m any, key any) (any, error) {
+	hashMap, ok := m.(*Map)
+	if !ok {
+		return nil, fmt.Errorf("delete expects a map, got %s", Stringify(m))
+	}
+
+	return hashMap.Delete(key)
+}
+
+// Str converts the value to the string print would show
+func Str(value any) (any, error) {
+	return Stringify(value), nil
+}
+
+func Keys(m any) (any, error) {
+	hashMap, ok := m.(*Map)
+	if !ok {
+		return nil, fmt.Errorf("keys expects a map, got %s", Stringify(m))
+	}
+
+	return NewList(hashMap.Keys()), nil
+}
+
+func Values(m any) (any, error) {
+	hashMap, ok := m.(*Map)
+	if !ok {
+		return nil, fmt.Errorf("values expects a map, got %s", Stringify(m))
+	}
+
+	return NewList(hashMap.Values()), nil
+}
@@ -0,0 +1,39 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Frame is a function call which was running
+// when a runtime error happened
+type Frame struct {
+	Function string
+	// File is empty when the code didn't come from a file
+	File string
+	Line int
+}
+
+func (f Frame) String() string {
+	if f.File == "" {
+		return fmt.Sprintf("at %s (line %d)", f.Function, f.Line)
+	}
+
+	return fmt.Sprintf("at %s (%s:%d)", f.Function, f.File, f.Line)
+}
+
+// Trace renders the frames, innermost call first,
+// nothing is rendered when the error happened at the top level
+func Trace(frames []Frame) string {
+	if len(frames) < 2 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Traceback (most recent call first):\n")
+	for _, frame := range frames {
+		b.WriteString("  " + frame.String() + "\n")
+	}
+
+	return b.String()
+}
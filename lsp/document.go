@@ -0,0 +1,130 @@
+package lsp
+
+import (
+	"net/url"
+	"sort"
+	"unicode/utf16"
+
+	"github.com/havrydotdev/golox/ast"
+	"github.com/havrydotdev/golox/diagnostics"
+	interp "github.com/havrydotdev/golox/interpreter"
+	"github.com/havrydotdev/golox/lox"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/resolver"
+	"github.com/havrydotdev/golox/scanner"
+	"github.com/havrydotdev/golox/token"
+)
+
+// document is an open file, it is analyzed
+// again every time its text changes
+type document struct {
+	uri  string
+	text string
+	// byte offsets where the lines start
+	lines  []int
+	tokens []token.Token
+	index  *index
+
+	diagnostics []Diagnostic
+}
+
+func newDocument(uri, text string, natives []lox.Native) *document {
+	d := &document{uri: uri, text: text, lines: []int{0}}
+	for i := range len(text) {
+		if text[i] == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+
+	d.analyze(natives)
+	return d
+}
+
+// analyze reports the errors of the first failing stage the
+// same way golox check does, the symbols are indexed even when
+// there are errors, from the statements which did parse
+func (d *document) analyze(natives []lox.Native) {
+	tokens, errs := scanner.NewFile(fileName(d.uri), d.text).Scan()
+	d.tokens = tokens
+
+	stmts, parseErrs := parser.New[ast.Expr, ast.Stmt](tokens, ast.Builder{}).Parse()
+	if len(errs) == 0 {
+		errs = parseErrs
+	}
+
+	if len(errs) == 0 {
+		res := resolver.New[struct{}, struct{}](interp.Nop{})
+		nodes := ast.Replay[resolver.Node[struct{}], resolver.Node[struct{}]](res, stmts)
+		_, errs = res.Resolve(nodes)
+	}
+
+	d.diagnostics = []Diagnostic{}
+	for _, err := range errs {
+		diag := diagnostics.From(err)
+		message := diag.Message
+		if diag.Hint != "" {
+			message += "\nhint: " + diag.Hint
+		}
+
+		d.diagnostics = append(d.diagnostics, Diagnostic{
+			Range:    d.tokenRange(diag.Token),
+			Severity: SeverityError,
+			Code:     diag.Code,
+			Source:   "golox",
+			Message:  message,
+		})
+	}
+
+	d.index = newIndex(d, stmts, natives)
+}
+
+// position converts a byte offset into a protocol position
+func (d *document) position(offset int) Position {
+	offset = min(max(offset, 0), len(d.text))
+	line := sort.Search(len(d.lines), func(i int) bool {
+		return d.lines[i] > offset
+	}) - 1
+
+	start := d.lines[line]
+	return Position{Line: line, Character: len(utf16.Encode([]rune(d.text[start:offset])))}
+}
+
+// offset converts a protocol position into a byte offset,
+// positions past the end of a line are clamped to it
+func (d *document) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	} else if pos.Line >= len(d.lines) {
+		return len(d.text)
+	}
+
+	start := d.lines[pos.Line]
+	units := 0
+	for i, r := range d.text[start:] {
+		if units >= pos.Character || r == '\n' {
+			return start + i
+		}
+
+		units += utf16.RuneLen(r)
+	}
+
+	return len(d.text)
+}
+
+func (d *document) tokenRange(tok token.Token) Range {
+	return Range{Start: d.position(tok.Start), End: d.position(tok.End)}
+}
+
+func (d *document) location(tok token.Token) Location {
+	return Location{URI: d.uri, Range: d.tokenRange(tok)}
+}
+
+// fileName is the path of a file URI, other URIs are kept as they are
+func fileName(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+
+	return u.Path
+}
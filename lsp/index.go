@@ -0,0 +1,422 @@
+package lsp
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/havrydotdev/golox/ast"
+	"github.com/havrydotdev/golox/lox"
+	"github.com/havrydotdev/golox/token"
+)
+
+type symbolKind int
+
+const (
+	variableSymbol symbolKind = iota
+	parameterSymbol
+	functionSymbol
+	classSymbol
+)
+
+func (k symbolKind) String() string {
+	switch k {
+	case parameterSymbol:
+		return "parameter"
+	case functionSymbol:
+		return "function"
+	case classSymbol:
+		return "class"
+	default:
+		return "variable"
+	}
+}
+
+// span is a range of byte offsets, end is exclusive
+type span struct {
+	start, end int
+}
+
+func (s span) contains(offset int) bool {
+	return s.start <= offset && offset < s.end
+}
+
+type symbol struct {
+	name string
+	kind symbolKind
+	// decl is a zero token for natives
+	decl   token.Token
+	detail string
+	global bool
+	// natives are the globals which aren't declared in the file
+	native bool
+	// where a local symbol can be used once it is declared
+	scope span
+}
+
+// occurrence is a declaration or a use of a symbol
+type occurrence struct {
+	tok    token.Token
+	symbol *symbol
+}
+
+// index binds every variable to its declaration with the same
+// scoping rules as the resolver, properties and methods are
+// looked up at runtime, so they are not indexed
+type index struct {
+	symbols []*symbol
+	// sorted by offset
+	occurrences []occurrence
+	outline     []*DocumentSymbol
+}
+
+// at finds the occurrence under the offset, the
+// offset right after an identifier still hits it
+func (idx *index) at(offset int) *occurrence {
+	i := sort.Search(len(idx.occurrences), func(i int) bool {
+		return idx.occurrences[i].tok.Start > offset
+	})
+
+	if i > 0 && offset <= idx.occurrences[i-1].tok.End {
+		return &idx.occurrences[i-1]
+	}
+
+	return nil
+}
+
+// references are the occurrences of the symbol in source order
+func (idx *index) references(sym *symbol, declaration bool) []token.Token {
+	var toks []token.Token
+	for _, occ := range idx.occurrences {
+		if occ.symbol == sym && (declaration || occ.tok != sym.decl) {
+			toks = append(toks, occ.tok)
+		}
+	}
+
+	return toks
+}
+
+// visible are the symbols which can be used at the offset,
+// an inner symbol shadows the outer ones with the same name
+func (idx *index) visible(offset int) []*symbol {
+	byName := map[string]*symbol{}
+	for _, sym := range idx.symbols {
+		if !sym.global && (!sym.scope.contains(offset) || sym.decl.End > offset) {
+			continue
+		}
+
+		if other, ok := byName[sym.name]; ok && (sym.global || other.scope.start > sym.scope.start) {
+			continue
+		}
+
+		byName[sym.name] = sym
+	}
+
+	syms := make([]*symbol, 0, len(byName))
+	for _, sym := range byName {
+		syms = append(syms, sym)
+	}
+
+	sort.Slice(syms, func(i, j int) bool {
+		return syms[i].name < syms[j].name
+	})
+
+	return syms
+}
+
+type scope struct {
+	symbols map[string]*symbol
+	// blocks have no tokens, so their span
+	// is only found once something is declared
+	span span
+}
+
+type indexer struct {
+	doc     *document
+	index   *index
+	scopes  []*scope
+	globals map[string]*symbol
+	// pairs of braces sorted by their start
+	braces []span
+	// the outline symbol of the enclosing function or class
+	parent *DocumentSymbol
+}
+
+func newIndex(doc *document, stmts []ast.Stmt, natives []lox.Native) *index {
+	i := &indexer{doc: doc, index: &index{}, globals: map[string]*symbol{}}
+	i.matchBraces()
+
+	for _, native := range natives {
+		kind, detail := variableSymbol, "var "+native.Name
+		if native.Params != nil {
+			kind, detail = functionSymbol, fmt.Sprintf("fun %s(%s)", native.Name, strings.Join(native.Params, ", "))
+		}
+
+		sym := i.newSymbol(native.Name, kind, token.Token{}, detail)
+		sym.native = true
+		i.globals[native.Name] = sym
+	}
+
+	// globals can be used before they are declared, in a function
+	// body which runs later, so they are declared up front
+	for _, stmt := range stmts {
+		switch n := stmt.(type) {
+		case *ast.Var:
+			i.declareGlobal(n.Name, variableSymbol, "var "+n.Name.Lexeme)
+		case *ast.Function:
+			i.declareGlobal(n.Name, functionSymbol, signature(n.Name, n.Params))
+		case *ast.Class:
+			i.declareGlobal(n.Name, classSymbol, classDetail(n))
+		}
+	}
+
+	ast.WalkStmts(stmts, i.visit)
+
+	sort.SliceStable(i.index.occurrences, func(a, b int) bool {
+		return i.index.occurrences[a].tok.Start < i.index.occurrences[b].tok.Start
+	})
+
+	return i.index
+}
+
+func (i *indexer) visit(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.Variable:
+		i.reference(n.Name)
+	case *ast.Assign:
+		i.reference(n.Name)
+	case *ast.Block:
+		i.beginScope(span{})
+		ast.WalkStmts(n.Stmts, i.visit)
+		i.endScope()
+		return false
+	case *ast.For:
+		i.beginScope(span{})
+		for _, child := range []ast.Node{n.Init, n.Cond, n.Incr, n.Body} {
+			ast.Walk(child, i.visit)
+		}
+		i.endScope()
+		return false
+	case *ast.Var:
+		if n.Init != nil {
+			ast.Walk(n.Init, i.visit)
+		}
+
+		i.declare(n.Name, variableSymbol, "var "+n.Name.Lexeme)
+		return false
+	case *ast.Lambda:
+		i.function(n.Keyword, n.Params, n.Body)
+		return false
+	case *ast.Function:
+		i.declare(n.Name, functionSymbol, signature(n.Name, n.Params))
+
+		enclosing := i.parent
+		i.parent = i.outline(n.Name, SymbolFunction, signature(n.Name, n.Params))
+		i.function(n.Name, n.Params, n.Body)
+		i.parent = enclosing
+		return false
+	case *ast.Class:
+		i.declare(n.Name, classSymbol, classDetail(n))
+		if n.Superclass != nil {
+			i.reference(n.Superclass.Name)
+		}
+
+		enclosing := i.parent
+		i.parent = i.outline(n.Name, SymbolClass, classDetail(n))
+		for _, method := range n.Methods {
+			class := i.parent
+			i.parent = i.outline(method.Name, SymbolMethod, signature(method.Name, method.Params))
+			i.function(method.Name, method.Params, method.Body)
+			i.parent = class
+		}
+		i.parent = enclosing
+		return false
+	}
+
+	return true
+}
+
+// function declares the parameters in a scope which
+// spans the body following the name or "fun" keyword
+func (i *indexer) function(name token.Token, params []token.Token, body []ast.Stmt) {
+	i.beginScope(i.bodyAfter(name))
+	for _, param := range params {
+		i.declare(param, parameterSymbol, "(parameter) "+param.Lexeme)
+	}
+
+	ast.WalkStmts(body, i.visit)
+	i.endScope()
+}
+
+func (i *indexer) declareGlobal(name token.Token, kind symbolKind, detail string) {
+	sym, ok := i.globals[name.Lexeme]
+	if !ok || sym.native {
+		if ok {
+			// a declaration replaces the native with the same name
+			i.index.symbols = slices.DeleteFunc(i.index.symbols, func(other *symbol) bool {
+				return other == sym
+			})
+		}
+
+		sym = i.newSymbol(name.Lexeme, kind, name, detail)
+		i.globals[name.Lexeme] = sym
+	}
+
+	i.occur(name, sym)
+}
+
+// declare adds the symbol to the innermost scope,
+// globals were already declared by newIndex
+func (i *indexer) declare(name token.Token, kind symbolKind, detail string) {
+	if len(i.scopes) == 0 {
+		return
+	}
+
+	scope := i.scopes[len(i.scopes)-1]
+	if scope.span == (span{}) {
+		scope.span = i.enclosing(name)
+	}
+
+	sym := i.newSymbol(name.Lexeme, kind, name, detail)
+	sym.global = false
+	sym.scope = scope.span
+	scope.symbols[name.Lexeme] = sym
+	i.occur(name, sym)
+}
+
+// reference binds the name to the innermost declaration,
+// names which are not declared anywhere are left out
+func (i *indexer) reference(name token.Token) {
+	for j := len(i.scopes) - 1; j >= 0; j-- {
+		if sym, ok := i.scopes[j].symbols[name.Lexeme]; ok {
+			i.occur(name, sym)
+			return
+		}
+	}
+
+	if sym, ok := i.globals[name.Lexeme]; ok {
+		i.occur(name, sym)
+	}
+}
+
+func (i *indexer) newSymbol(name string, kind symbolKind, decl token.Token, detail string) *symbol {
+	sym := &symbol{name: name, kind: kind, decl: decl, detail: detail, global: true}
+	i.index.symbols = append(i.index.symbols, sym)
+	return sym
+}
+
+func (i *indexer) occur(tok token.Token, sym *symbol) {
+	i.index.occurrences = append(i.index.occurrences, occurrence{tok, sym})
+}
+
+func (i *indexer) outline(name token.Token, kind int, detail string) *DocumentSymbol {
+	start := name
+	if kind != SymbolMethod {
+		// functions and classes start at their keyword
+		if j := i.tokenIndex(name); j > 0 {
+			start = i.doc.tokens[j-1]
+		}
+	}
+
+	sym := &DocumentSymbol{
+		Name:   name.Lexeme,
+		Detail: detail,
+		Kind:   kind,
+		Range: Range{
+			Start: i.doc.position(start.Start),
+			End:   i.doc.position(i.bodyAfter(name).end),
+		},
+		SelectionRange: i.doc.tokenRange(name),
+	}
+
+	if i.parent == nil {
+		i.index.outline = append(i.index.outline, sym)
+	} else {
+		i.parent.Children = append(i.parent.Children, sym)
+	}
+
+	return sym
+}
+
+func (i *indexer) beginScope(span span) {
+	i.scopes = append(i.scopes, &scope{symbols: map[string]*symbol{}, span: span})
+}
+
+func (i *indexer) endScope() {
+	i.scopes = i.scopes[:len(i.scopes)-1]
+}
+
+// matchBraces pairs the braces of the document, the ones
+// which are never closed span to the end of the text
+func (i *indexer) matchBraces() {
+	var open []int
+	for _, tok := range i.doc.tokens {
+		switch tok.Kind {
+		case token.LeftBrace:
+			open = append(open, len(i.braces))
+			i.braces = append(i.braces, span{tok.Start, i.end()})
+		case token.RightBrace:
+			if len(open) != 0 {
+				i.braces[open[len(open)-1]].end = tok.End
+				open = open[:len(open)-1]
+			}
+		}
+	}
+}
+
+// enclosing is the innermost pair of braces around the token
+func (i *indexer) enclosing(tok token.Token) span {
+	around := span{0, i.end()}
+	for _, braces := range i.braces {
+		if braces.start > tok.Start {
+			break
+		}
+
+		if braces.contains(tok.Start) {
+			around = braces
+		}
+	}
+
+	return around
+}
+
+// bodyAfter is the first pair of braces after the token
+func (i *indexer) bodyAfter(tok token.Token) span {
+	for _, braces := range i.braces {
+		if braces.start >= tok.End {
+			return braces
+		}
+	}
+
+	return span{tok.Start, i.end()}
+}
+
+func (i *indexer) tokenIndex(tok token.Token) int {
+	return sort.Search(len(i.doc.tokens), func(j int) bool {
+		return i.doc.tokens[j].Start >= tok.Start
+	})
+}
+
+// end is past the end of the text, so a cursor
+// at the very end is still inside an open scope
+func (i *indexer) end() int {
+	return len(i.doc.text) + 1
+}
+
+func signature(name token.Token, params []token.Token) string {
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.Lexeme
+	}
+
+	return fmt.Sprintf("fun %s(%s)", name.Lexeme, strings.Join(names, ", "))
+}
+
+func classDetail(class *ast.Class) string {
+	if class.Superclass == nil {
+		return "class " + class.Name.Lexeme
+	}
+
+	return fmt.Sprintf("class %s < %s", class.Name.Lexeme, class.Superclass.Name.Lexeme)
+}
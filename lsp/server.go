@@ -0,0 +1,350 @@
+// Package lsp is a language server for lox files, it speaks
+// the Language Server Protocol with JSON-RPC over a stream
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/havrydotdev/golox/lox"
+)
+
+// Server handles the messages of one client,
+// documents are analyzed whenever they change
+type Server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	docs map[string]*document
+	// the globals which the documents use without declaring them
+	natives []lox.Native
+
+	shutdown bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{in: bufio.NewReader(in), out: out, docs: map[string]*document{}, natives: lox.Natives}
+}
+
+// Define makes the globals a host defines besides the natives known
+// to the documents, like the ones of golox.Engine.Defined, it has
+// to be called before Serve
+func (s *Server) Define(globals ...lox.Native) {
+	s.natives = append(slices.Clip(s.natives), globals...)
+}
+
+type handler func(s *Server, params json.RawMessage) (any, error)
+
+var handlers = map[string]handler{
+	"initialize":                  (*Server).initialize,
+	"shutdown":                    (*Server).shutdownRequest,
+	"textDocument/definition":     (*Server).definition,
+	"textDocument/references":     (*Server).references,
+	"textDocument/hover":          (*Server).hover,
+	"textDocument/documentSymbol": (*Server).documentSymbol,
+	"textDocument/completion":     (*Server).completion,
+}
+
+type notification func(s *Server, params json.RawMessage) error
+
+var notifications = map[string]notification{
+	"textDocument/didOpen":   (*Server).didOpen,
+	"textDocument/didChange": (*Server).didChange,
+	"textDocument/didClose":  (*Server).didClose,
+}
+
+// Serve handles messages until the client sends "exit", it fails
+// if the stream breaks or the client exits without a shutdown
+func (s *Server) Serve() error {
+	for {
+		body, err := readMessage(s.in)
+		if errors.Is(err, io.EOF) {
+			return errors.New("the client closed the stream without exiting")
+		} else if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := s.reply(nil, nil, &responseError{codeParseError, err.Error()}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("the client exited without a shutdown")
+			}
+
+			return nil
+		}
+
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle only fails if the reply can't be written, errors
+// of the requests are sent back and the ones of notifications
+// are dropped, as the protocol has no way to report them
+func (s *Server) handle(msg message) error {
+	if msg.ID == nil {
+		if notify, ok := notifications[msg.Method]; ok && !s.shutdown {
+			if err := notify(s, msg.Params); err != nil {
+				var rerr *responseError
+				if !errors.As(err, &rerr) {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	handle, ok := handlers[msg.Method]
+	switch {
+	case msg.Method == "":
+		// responses to requests we never send
+		return nil
+	case s.shutdown:
+		return s.reply(msg.ID, nil, &responseError{codeInvalidRequest, "the server is shut down"})
+	case !ok:
+		return s.reply(msg.ID, nil, &responseError{codeMethodNotFound, "unknown method " + msg.Method})
+	}
+
+	result, err := handle(s, msg.Params)
+	if err != nil {
+		var rerr *responseError
+		if !errors.As(err, &rerr) {
+			rerr = &responseError{codeInvalidRequest, err.Error()}
+		}
+
+		return s.reply(msg.ID, nil, rerr)
+	}
+
+	return s.reply(msg.ID, result, nil)
+}
+
+func (s *Server) reply(id json.RawMessage, result any, rerr *responseError) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	msg := message{ID: id, Error: rerr}
+	if rerr == nil {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+
+		msg.Result = raw
+	}
+
+	return writeMessage(s.out, msg)
+}
+
+func (s *Server) notify(method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	return writeMessage(s.out, message{Method: method, Params: raw})
+}
+
+func decode[T any](params json.RawMessage) (T, error) {
+	var v T
+	if err := json.Unmarshal(params, &v); err != nil {
+		return v, &responseError{codeInvalidParams, err.Error()}
+	}
+
+	return v, nil
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	return map[string]any{
+		"capabilities": map[string]any{
+			// full documents are sent on every change
+			"textDocumentSync":       map[string]any{"openClose": true, "change": 1},
+			"definitionProvider":     true,
+			"referencesProvider":     true,
+			"hoverProvider":          true,
+			"documentSymbolProvider": true,
+			"completionProvider":     map[string]any{},
+		},
+		"serverInfo": map[string]any{"name": "golox"},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(params json.RawMessage) (any, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) error {
+	p, err := decode[DidOpenTextDocumentParams](params)
+	if err != nil {
+		return err
+	}
+
+	return s.update(p.TextDocument.URI, p.TextDocument.Text)
+}
+
+func (s *Server) didChange(params json.RawMessage) error {
+	p, err := decode[DidChangeTextDocumentParams](params)
+	if err != nil {
+		return err
+	}
+
+	if len(p.ContentChanges) == 0 {
+		return nil
+	}
+
+	return s.update(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+}
+
+func (s *Server) didClose(params json.RawMessage) error {
+	p, err := decode[DidCloseTextDocumentParams](params)
+	if err != nil {
+		return err
+	}
+
+	delete(s.docs, p.TextDocument.URI)
+	// the errors of a closed file are cleared
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+func (s *Server) update(uri, text string) error {
+	doc := newDocument(uri, text, s.natives)
+	s.docs[uri] = doc
+
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: doc.diagnostics,
+	})
+}
+
+// lookup finds the open document and the occurrence
+// of a symbol at the position, if there is one
+func (s *Server) lookup(p TextDocumentPositionParams) (*document, *occurrence, error) {
+	doc, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil, nil, &responseError{codeInvalidParams, "unknown document " + p.TextDocument.URI}
+	}
+
+	return doc, doc.index.at(doc.offset(p.Position)), nil
+}
+
+func (s *Server) definition(params json.RawMessage) (any, error) {
+	p, err := decode[TextDocumentPositionParams](params)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, occ, err := s.lookup(p)
+	if err != nil || occ == nil || occ.symbol.native {
+		return nil, err
+	}
+
+	return doc.location(occ.symbol.decl), nil
+}
+
+func (s *Server) references(params json.RawMessage) (any, error) {
+	p, err := decode[ReferenceParams](params)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, occ, err := s.lookup(p.TextDocumentPositionParams)
+	if err != nil || occ == nil {
+		return nil, err
+	}
+
+	locations := []Location{}
+	for _, tok := range doc.index.references(occ.symbol, p.Context.IncludeDeclaration) {
+		locations = append(locations, doc.location(tok))
+	}
+
+	return locations, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (any, error) {
+	p, err := decode[TextDocumentPositionParams](params)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, occ, err := s.lookup(p)
+	if err != nil || occ == nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "```lox\n%s\n```\n", occ.symbol.detail)
+	if occ.symbol.native {
+		text.WriteString("native " + occ.symbol.kind.String())
+	} else {
+		fmt.Fprintf(&text, "%s declared at %s", occ.symbol.kind, occ.symbol.decl.Pos())
+	}
+
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: text.String()},
+		Range:    doc.tokenRange(occ.tok),
+	}, nil
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (any, error) {
+	p, err := decode[DocumentSymbolParams](params)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil, &responseError{codeInvalidParams, "unknown document " + p.TextDocument.URI}
+	}
+
+	outline := doc.index.outline
+	if outline == nil {
+		outline = []*DocumentSymbol{}
+	}
+
+	return outline, nil
+}
+
+func (s *Server) completion(params json.RawMessage) (any, error) {
+	p, err := decode[TextDocumentPositionParams](params)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil, &responseError{codeInvalidParams, "unknown document " + p.TextDocument.URI}
+	}
+
+	items := []CompletionItem{}
+	for _, sym := range doc.index.visible(doc.offset(p.Position)) {
+		kind := CompletionVariable
+		switch sym.kind {
+		case functionSymbol:
+			kind = CompletionFunction
+		case classSymbol:
+			kind = CompletionClass
+		}
+
+		items = append(items, CompletionItem{Label: sym.name, Kind: kind, Detail: sym.detail})
+	}
+
+	return items, nil
+}
@@ -0,0 +1,276 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/havrydotdev/golox/lox"
+)
+
+const uri = "file:///tmp/main.lox"
+
+const source = `var total = 0;
+fun add(n) {
+  total = total + n;
+  return total;
+}
+
+class Counter {
+  inc() { return add(1); }
+}
+
+add(2);
+print(total);
+`
+
+// client scripts a session, every request gets
+// its position in the session as its id
+type client struct {
+	in       bytes.Buffer
+	requests int
+}
+
+func (c *client) request(method string, params any) int {
+	c.requests++
+	c.send(map[string]any{"jsonrpc": "2.0", "id": c.requests, "method": method, "params": params})
+	return c.requests
+}
+
+func (c *client) notify(method string, params any) {
+	c.send(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (c *client) send(msg any) {
+	body, _ := json.Marshal(msg)
+	fmt.Fprintf(&c.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func at(line, character int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": character},
+	}
+}
+
+// serve runs the script and returns the responses
+// by id and the notifications in order
+func serve(t *testing.T, c *client) (map[int]message, []message) {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := NewServer(&c.in, &out).Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := map[int]message{}
+	var notifications []message
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(r)
+		if err == io.EOF {
+			return responses, notifications
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+
+		if msg.ID == nil {
+			notifications = append(notifications, msg)
+			continue
+		}
+
+		var id int
+		if err := json.Unmarshal(msg.ID, &id); err != nil {
+			t.Fatal(err)
+		}
+
+		responses[id] = msg
+	}
+}
+
+func result[T any](t *testing.T, msg message) T {
+	t.Helper()
+
+	if msg.Error != nil {
+		t.Fatalf("unexpected error %v", msg.Error)
+	}
+
+	var v T
+	if err := json.Unmarshal(msg.Result, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+func TestSession(t *testing.T) {
+	var c client
+	initialize := c.request("initialize", map[string]any{"capabilities": map[string]any{}})
+	c.notify("initialized", map[string]any{})
+	c.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "lox", "version": 1, "text": source},
+	})
+
+	definition := c.request("textDocument/definition", at(7, 17))
+	native := c.request("textDocument/definition", at(11, 0))
+	refs := at(2, 3)
+	refs["context"] = map[string]any{"includeDeclaration": true}
+	references := c.request("textDocument/references", refs)
+	hover := c.request("textDocument/hover", at(2, 18))
+	symbols := c.request("textDocument/documentSymbol", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+	})
+	completion := c.request("textDocument/completion", at(3, 2))
+	unknown := c.request("textDocument/rename", at(0, 0))
+
+	c.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []any{map[string]any{"text": "var a = ;\nprint(a)"}},
+	})
+
+	shutdown := c.request("shutdown", nil)
+	c.notify("exit", nil)
+
+	responses, notifications := serve(t, &c)
+
+	caps := result[map[string]map[string]any](t, responses[initialize])["capabilities"]
+	if caps["definitionProvider"] != true || caps["completionProvider"] == nil {
+		t.Errorf("unexpected capabilities %v", caps)
+	}
+
+	loc := result[Location](t, responses[definition])
+	if loc.URI != uri || loc.Range != (Range{Position{1, 4}, Position{1, 7}}) {
+		t.Errorf("expected the declaration of add, got %v", loc)
+	}
+
+	if raw := string(responses[native].Result); raw != "null" {
+		t.Errorf("expected no definition of a native, got %s", raw)
+	}
+
+	locations := result[[]Location](t, responses[references])
+	lines := []int{0, 2, 2, 3, 11}
+	if len(locations) != len(lines) {
+		t.Fatalf("expected %d references, got %v", len(lines), locations)
+	}
+
+	for i, line := range lines {
+		if locations[i].Range.Start.Line != line {
+			t.Errorf("expected reference %d on line %d, got %v", i, line, locations[i])
+		}
+	}
+
+	contents := result[Hover](t, responses[hover]).Contents.Value
+	if !strings.Contains(contents, "(parameter) n") || !strings.Contains(contents, "declared at /tmp/main.lox:2:9") {
+		t.Errorf("unexpected hover %q", contents)
+	}
+
+	outline := result[[]DocumentSymbol](t, responses[symbols])
+	if len(outline) != 2 || outline[0].Name != "add" || outline[1].Kind != SymbolClass ||
+		len(outline[1].Children) != 1 || outline[1].Children[0].Name != "inc" {
+		t.Errorf("unexpected symbols %+v", outline)
+	}
+
+	if outline[0].Range != (Range{Position{1, 0}, Position{4, 1}}) {
+		t.Errorf("unexpected range of add %v", outline[0].Range)
+	}
+
+	labels := map[string]bool{}
+	for _, item := range result[[]CompletionItem](t, responses[completion]) {
+		labels[item.Label] = true
+	}
+
+	for _, label := range []string{"n", "total", "add", "Counter", "print"} {
+		if !labels[label] {
+			t.Errorf("expected %s to be completed, got %v", label, labels)
+		}
+	}
+
+	if rerr := responses[unknown].Error; rerr == nil || rerr.Code != codeMethodNotFound {
+		t.Errorf("expected method not found, got %v", rerr)
+	}
+
+	if _, ok := responses[shutdown]; !ok {
+		t.Error("shutdown wasn't answered")
+	}
+
+	if len(notifications) != 2 {
+		t.Fatalf("expected diagnostics on open and change, got %v", notifications)
+	}
+
+	var opened, changed PublishDiagnosticsParams
+	json.Unmarshal(notifications[0].Params, &opened)
+	json.Unmarshal(notifications[1].Params, &changed)
+	if len(opened.Diagnostics) != 0 {
+		t.Errorf("expected no errors, got %v", opened.Diagnostics)
+	}
+
+	if len(changed.Diagnostics) != 2 || changed.Diagnostics[0].Range.Start != (Position{0, 8}) {
+		t.Errorf("unexpected diagnostics %+v", changed.Diagnostics)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	doc := newDocument(uri, `var a = 1;
+{
+  var a = "shadow";
+  fun f(x) { return a + x; }
+}
+print(a);
+`, lox.Natives)
+
+	inner := doc.index.at(doc.offset(Position{3, 20}))
+	if inner == nil || inner.symbol.decl.Line != 3 {
+		t.Errorf("expected the block's a, got %v", inner)
+	}
+
+	outer := doc.index.at(doc.offset(Position{5, 6}))
+	if outer == nil || outer.symbol.decl.Line != 1 {
+		t.Errorf("expected the global a, got %v", outer)
+	}
+
+	for _, sym := range doc.index.visible(doc.offset(Position{5, 0})) {
+		if sym.name == "f" || sym.name == "x" {
+			t.Errorf("%s is not visible outside its block", sym.name)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	doc := newDocument(uri, "var s = \"é😀\"; s;\n", lox.Natives)
+
+	// é is one code unit and 😀 two, but 2 and 4 bytes
+	pos := Position{0, 15}
+	offset := doc.offset(pos)
+	if offset != 18 || doc.position(offset) != pos {
+		t.Errorf("expected offset 18, got %d", offset)
+	}
+}
+
+func TestDefine(t *testing.T) {
+	s := NewServer(strings.NewReader(""), io.Discard)
+	s.Define(lox.Native{Name: "limit"}, lox.Native{Name: "divide", Params: []string{"float64", "float64"}})
+	if len(lox.Natives) == len(s.natives) {
+		t.Fatal("expected the globals to be added")
+	}
+
+	doc := newDocument(uri, "print(divide(limit, 2));\n", s.natives)
+	for name, detail := range map[string]string{
+		"print":  "fun print(value)",
+		"divide": "fun divide(float64, float64)",
+		"limit":  "var limit",
+	} {
+		occ := doc.index.at(strings.Index(doc.text, name))
+		if occ == nil || !occ.symbol.native || occ.symbol.detail != detail {
+			t.Errorf("expected %s to be a native %q, got %v", name, detail, occ)
+		}
+	}
+}
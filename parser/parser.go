@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"errors"
 	"fmt"
 	"slices"
 
@@ -9,13 +8,28 @@ import (
 	"github.com/havrydotdev/golox/token"
 )
 
-type ErrorSet[E any] struct {
-	name   token.Token
-	object E
+type Error struct {
+	Token   token.Token
+	Message string
+	// Hint suggests how to fix the error, it can be empty
+	Hint string
 }
 
-func (e ErrorSet[E]) Error() string {
-	return "set error"
+func (e Error) Error() string {
+	if e.Token.Kind == token.Eof {
+		return fmt.Sprintf("[line %d] Error at end: %s", e.Token.Line, e.Message)
+	}
+
+	return fmt.Sprintf("[line %d] Error at '%s': %s", e.Token.Line, e.Token.Lexeme, e.Message)
+}
+
+// target is the last expression parsed by call which can be
+// assigned to, assign builds the assignment of the value to it
+type target[E any] struct {
+	// positions of the first token of the
+	// expression and of the one right after it
+	start, end uint
+	assign     func(value E) E
 }
 
 type Parser[E any, S any] struct {
@@ -23,6 +37,10 @@ type Parser[E any, S any] struct {
 	errors  []error
 	tokens  []token.Token
 	alg     interp.Alg[E, S]
+
+	// set while parsing the body of an init method
+	initializer bool
+	target      *target[E]
 }
 
 func New[E any, S any](tokens []token.Token, alg interp.Alg[E, S]) *Parser[E, S] {
@@ -45,11 +63,38 @@ func (p *Parser[E, S]) Parse() ([]S, []error) {
 	return stmts, p.errors
 }
 
+// ParseExpression parses the tokens as a single expression,
+// it fails if anything but the end of input follows it
+func (p *Parser[E, S]) ParseExpression() (E, error) {
+	expr, err := p.expression()
+	if err != nil {
+		return p.alg.NilExpr(), err
+	}
+
+	if !p.isAtEnd() {
+		return p.alg.NilExpr(), p.error(p.peek(), "unexpected token after expression.")
+	}
+
+	return expr, nil
+}
+
 func (p *Parser[E, S]) declaration() (S, error) {
+	start := p.peek()
+	stmt, err := p.unlocatedDeclaration()
+	if err != nil {
+		return stmt, err
+	}
+
+	return p.locate(start, stmt), nil
+}
+
+func (p *Parser[E, S]) unlocatedDeclaration() (S, error) {
 	switch {
 	case p.match(token.Class):
 		return p.classDeclaration()
-	case p.match(token.Fun):
+	case p.check(token.Fun) && p.checkNext(token.Identifier):
+		// "fun" without a name starts a lambda expression
+		p.advance()
 		return p.function("function")
 	case p.match(token.Var):
 		return p.varDeclaration()
@@ -64,7 +109,17 @@ func (p *Parser[E, S]) classDeclaration() (S, error) {
 		return p.alg.NilStmt(), err
 	}
 
-	_, err = p.consume(token.LeftBrace, "expected '}' before class body.")
+	var superclass E
+	if p.match(token.Less) {
+		_, err = p.consume(token.Identifier, "expected superclass name.")
+		if err != nil {
+			return p.alg.NilStmt(), err
+		}
+
+		superclass = p.alg.Variable(p.previous())
+	}
+
+	_, err = p.consume(token.LeftBrace, "expected '{' before class body.")
 	if err != nil {
 		return p.alg.NilStmt(), err
 	}
@@ -84,7 +139,7 @@ func (p *Parser[E, S]) classDeclaration() (S, error) {
 		return p.alg.NilStmt(), err
 	}
 
-	return p.alg.Class(name, methods), nil
+	return p.alg.Class(name, superclass, methods), nil
 }
 
 func (p *Parser[E, S]) function(kind string) (S, error) {
@@ -98,16 +153,42 @@ func (p *Parser[E, S]) function(kind string) (S, error) {
 		return p.alg.NilStmt(), err
 	}
 
+	params, body, err := p.functionBody(kind == "method" && name.Lexeme == "init")
+	if err != nil {
+		return p.alg.NilStmt(), err
+	}
+
+	return p.alg.Function(name, params, body), nil
+}
+
+func (p *Parser[E, S]) lambda() (E, error) {
+	keyword := p.previous()
+	_, err := p.consume(token.LeftParen, "expected '(' after 'fun'.")
+	if err != nil {
+		return p.alg.NilExpr(), err
+	}
+
+	params, body, err := p.functionBody(false)
+	if err != nil {
+		return p.alg.NilExpr(), err
+	}
+
+	return p.alg.Lambda(keyword, params, body), nil
+}
+
+// functionBody parses the parameters and the body
+// of a function, the '(' must be already consumed
+func (p *Parser[E, S]) functionBody(initializer bool) ([]token.Token, []S, error) {
 	var params []token.Token
 	if !p.check(token.RightParen) {
 		for {
 			if len(params) >= 255 {
-				return p.alg.NilStmt(), errors.New("cant have more than 255 parameters.")
+				return nil, nil, p.error(p.peek(), "can't have more than 255 parameters.")
 			}
 
 			paramName, err := p.consume(token.Identifier, "expected identifier name.")
 			if err != nil {
-				return p.alg.NilStmt(), err
+				return nil, nil, err
 			}
 
 			params = append(params, paramName)
@@ -118,24 +199,31 @@ func (p *Parser[E, S]) function(kind string) (S, error) {
 		}
 	}
 
-	_, err = p.consume(token.RightParen, "expected ')' after parameters")
+	_, err := p.consume(token.RightParen, "expected ')' after parameters")
 	if err != nil {
-		return p.alg.NilStmt(), err
+		return nil, nil, err
 	}
 
 	_, err = p.consume(token.LeftBrace, "expected '{' before function body")
 	if err != nil {
-		return p.alg.NilStmt(), err
+		return nil, nil, err
 	}
 
+	enclosing := p.initializer
+	p.initializer = initializer
+	defer func() { p.initializer = enclosing }()
+
 	body, err := p.blockStmts()
 	if err != nil {
-		return p.alg.NilStmt(), err
+		return nil, nil, err
 	}
 
 	_, err = p.consume(token.RightBrace, "expected '}' after function body.")
+	if err != nil {
+		return nil, nil, err
+	}
 
-	return p.alg.Function(name, params, body), nil
+	return params, body, nil
 }
 
 func (p *Parser[E, S]) varDeclaration() (S, error) {
@@ -144,7 +232,7 @@ func (p *Parser[E, S]) varDeclaration() (S, error) {
 		return p.alg.NilStmt(), err
 	}
 
-	init := p.alg.Literal(nil)
+	var init E
 	if p.match(token.Equal) {
 		init, err = p.expression()
 		if err != nil {
@@ -157,35 +245,34 @@ func (p *Parser[E, S]) varDeclaration() (S, error) {
 	return p.alg.Var(name, init), err
 }
 
-// how tf am i supposed to figure this out
-// we cant get kind of function (possible with kinds in interp package)
-// we cant access p.alg.Get fields when its returned (because its a function)
-// the only thing i see here is to return error for now
+// the algebra can't tell what kind of expression it built,
+// so call remembers which tokens the last assignable expression
+// spans, '=' is only valid right after the target which
+// starts where the assignment does
 func (p *Parser[E, S]) assignment() (E, error) {
+	start := p.current
 	expr, err := p.or()
 	if err != nil {
-		if err, ok := err.(ErrorSet[E]); ok {
-			if p.match(token.Equal) {
-				value, errNew := p.assignment()
-				if errNew != nil {
-					return p.alg.NilExpr(), err
-				}
+		return p.alg.NilExpr(), err
+	}
 
-				return p.alg.Set(err.object, err.name, value), nil
+	if p.match(token.Equal) {
+		equals := p.previous()
+		target := p.target
+		if target == nil || target.start != start || target.end != p.current-1 {
+			return p.alg.NilExpr(), Error{
+				Token:   equals,
+				Message: "invalid assignment target.",
+				Hint:    "only variables, fields and list or map elements can be assigned to",
 			}
 		}
 
-		return p.alg.Literal(nil), err
-	}
-
-	name := p.previous()
-	if p.match(token.Equal) {
 		value, err := p.assignment()
 		if err != nil {
-			return p.alg.Literal(nil), err
+			return p.alg.NilExpr(), err
 		}
 
-		return p.alg.Assign(name, value), nil
+		return target.assign(value), nil
 	}
 
 	return expr, nil
@@ -216,9 +303,9 @@ func (p *Parser[E, S]) and() (E, error) {
 		return p.alg.Literal(nil), err
 	}
 
-	for p.match(token.Or) {
+	for p.match(token.And) {
 		op := p.previous()
-		right, err := p.and()
+		right, err := p.equality()
 		if err != nil {
 			return p.alg.Literal(nil), err
 		}
@@ -235,6 +322,10 @@ func (p *Parser[E, S]) statement() (S, error) {
 		return p.forStatement()
 	case p.match(token.Return):
 		return p.returnStatement()
+	case p.match(token.Break):
+		return p.loopJump(p.alg.Break)
+	case p.match(token.Continue):
+		return p.loopJump(p.alg.Continue)
 	case p.match(token.If):
 		return p.ifStatement()
 	case p.match(token.While):
@@ -256,6 +347,15 @@ func (p *Parser[E, S]) returnStatement() (S, error) {
 		if err != nil {
 			return p.alg.NilStmt(), err
 		}
+
+		// not fatal, the rest of the class can still be parsed
+		if p.initializer {
+			p.errors = append(p.errors, Error{
+				Token:   keyword,
+				Message: "can't return a value from an initializer.",
+				Hint:    "init always returns 'this', use 'return;' to leave it early",
+			})
+		}
 	}
 
 	_, err = p.consume(token.Semicolon, "expected ';' after return")
@@ -263,13 +363,21 @@ func (p *Parser[E, S]) returnStatement() (S, error) {
 	return p.alg.Return(keyword, value), err
 }
 
+// loopJump parses break and continue statements
+func (p *Parser[E, S]) loopJump(stmt func(keyword token.Token) S) (S, error) {
+	keyword := p.previous()
+	_, err := p.consume(token.Semicolon, fmt.Sprintf("expected ';' after '%s'.", keyword.Lexeme))
+
+	return stmt(keyword), err
+}
+
 func (p *Parser[E, S]) forStatement() (S, error) {
 	_, err := p.consume(token.LeftParen, "expected '(' after 'for'.")
 	if err != nil {
 		return p.alg.NilStmt(), err
 	}
 
-	init := p.alg.ExprStatement(p.alg.Literal(nil))
+	var init S
 	if p.match(token.Var) {
 		init, err = p.varDeclaration()
 	} else if !p.match(token.Semicolon) {
@@ -280,7 +388,7 @@ func (p *Parser[E, S]) forStatement() (S, error) {
 		return p.alg.NilStmt(), err
 	}
 
-	cond := p.alg.Literal(true)
+	var cond E
 	if !p.check(token.Semicolon) {
 		cond, err = p.expression()
 		if err != nil {
@@ -293,7 +401,7 @@ func (p *Parser[E, S]) forStatement() (S, error) {
 		return p.alg.NilStmt(), err
 	}
 
-	incr := p.alg.Literal(nil)
+	var incr E
 	if !p.check(token.RightParen) {
 		incr, err = p.expression()
 		if err != nil {
@@ -306,35 +414,31 @@ func (p *Parser[E, S]) forStatement() (S, error) {
 		return p.alg.NilStmt(), err
 	}
 
-	body, err := p.statement()
+	body, err := p.body()
 	if err != nil {
 		return p.alg.NilStmt(), err
 	}
 
-	body = p.alg.Block([]S{body, p.alg.ExprStatement(incr)})
-	body = p.alg.While(cond, body)
-	body = p.alg.Block([]S{init, body})
-
-	return body, nil
+	return p.alg.For(init, cond, incr, body), nil
 }
 
 func (p *Parser[E, S]) whileStatement() (S, error) {
 	_, err := p.consume(token.LeftParen, "expected '(' after 'while'.")
 	if err != nil {
-		return p.alg.NilStmt(), nil
+		return p.alg.NilStmt(), err
 	}
 
 	cond, err := p.expression()
 	if err != nil {
-		return p.alg.NilStmt(), nil
+		return p.alg.NilStmt(), err
 	}
 
 	_, err = p.consume(token.RightParen, "expected ')' after condition.")
 	if err != nil {
-		return p.alg.NilStmt(), nil
+		return p.alg.NilStmt(), err
 	}
 
-	body, err := p.statement()
+	body, err := p.body()
 
 	return p.alg.While(cond, body), err
 }
@@ -355,19 +459,41 @@ func (p *Parser[E, S]) ifStatement() (S, error) {
 		return p.alg.NilStmt(), err
 	}
 
-	then, err := p.statement()
+	then, err := p.body()
 	if err != nil {
 		return p.alg.NilStmt(), err
 	}
 
 	var _else S
 	if p.match(token.Else) {
-		_else, err = p.statement()
+		_else, err = p.body()
 	}
 
 	return p.alg.If(cond, then, _else), err
 }
 
+// body parses the statement of an if, while or for
+func (p *Parser[E, S]) body() (S, error) {
+	start := p.peek()
+	stmt, err := p.statement()
+	if err != nil {
+		return stmt, err
+	}
+
+	return p.locate(start, stmt), nil
+}
+
+// locate tells the algebra where the statement starts if it
+// implements interp.Positioned, blocks are left as they are
+func (p *Parser[E, S]) locate(start token.Token, stmt S) S {
+	alg, ok := p.alg.(interp.Positioned[S])
+	if !ok || start.Kind == token.LeftBrace {
+		return stmt
+	}
+
+	return alg.At(start, stmt)
+}
+
 func (p *Parser[E, S]) blockStmts() ([]S, error) {
 	var stmts []S
 	for !p.check(token.RightBrace) && !p.isAtEnd() {
@@ -488,45 +614,80 @@ func (p *Parser[E, S]) factor() (E, error) {
 
 func (p *Parser[E, S]) unary() (E, error) {
 	if p.match(token.Bang, token.Minus) {
+		op := p.previous()
 		right, err := p.unary()
 		if err != nil {
 			return p.alg.Literal(nil), err
 		}
 
-		return p.alg.Unary(p.previous(), right), nil
+		return p.alg.Unary(op, right), nil
 	}
 
 	return p.call()
 }
 
 func (p *Parser[E, S]) call() (E, error) {
+	start := p.current
 	expr, err := p.primary()
 	if err != nil {
 		return p.alg.NilExpr(), err
 	}
 
+	var assign func(value E) E
+	if name := p.previous(); name.Kind == token.Identifier && p.current-1 == start {
+		assign = func(value E) E {
+			return p.alg.Assign(name, value)
+		}
+	}
+
 	for {
 		if p.match(token.LeftParen) {
 			expr, err = p.finishCall(expr)
 			if err != nil {
 				return p.alg.NilExpr(), err
 			}
+
+			assign = nil
 		} else if p.match(token.Dot) {
 			name, err := p.consume(token.Identifier, "expected property name after '.'.")
 			if err != nil {
 				return p.alg.NilExpr(), err
 			}
 
-			if p.peek().Kind == token.Equal {
-				return expr, ErrorSet[E]{name, expr}
+			object := expr
+			assign = func(value E) E {
+				return p.alg.Set(object, name, value)
 			}
 
 			expr = p.alg.Get(name, expr)
+		} else if p.match(token.LeftBracket) {
+			bracket := p.previous()
+			index, err := p.expression()
+			if err != nil {
+				return p.alg.NilExpr(), err
+			}
+
+			_, err = p.consume(token.RightBracket, "expected ']' after index.")
+			if err != nil {
+				return p.alg.NilExpr(), err
+			}
+
+			object := expr
+			assign = func(value E) E {
+				return p.alg.SetIndex(object, bracket, index, value)
+			}
+
+			expr = p.alg.Index(object, bracket, index)
 		} else {
 			break
 		}
 	}
 
+	p.target = nil
+	if assign != nil {
+		p.target = &target[E]{start, p.current, assign}
+	}
+
 	return expr, nil
 }
 
@@ -536,7 +697,7 @@ func (p *Parser[E, S]) finishCall(callee E) (E, error) {
 	if !p.check(token.RightParen) {
 		for {
 			if len(args) >= 255 {
-				return p.alg.Literal(nil), errors.New("can't have more than 255 arguments")
+				return p.alg.Literal(nil), p.error(p.peek(), "can't have more than 255 arguments.")
 			}
 
 			expr, err := p.expression()
@@ -562,8 +723,25 @@ func (p *Parser[E, S]) finishCall(callee E) (E, error) {
 
 func (p *Parser[E, S]) primary() (E, error) {
 	switch {
+	case p.match(token.This):
+		return p.alg.This(p.previous()), nil
+	case p.match(token.Super):
+		keyword := p.previous()
+		_, err := p.consume(token.Dot, "expected '.' after 'super'.")
+		if err != nil {
+			return p.alg.NilExpr(), err
+		}
+
+		method, err := p.consume(token.Identifier, "expected superclass method name.")
+		if err != nil {
+			return p.alg.NilExpr(), err
+		}
+
+		return p.alg.Super(keyword, method), nil
 	case p.match(token.Identifier):
 		return p.alg.Variable(p.previous()), nil
+	case p.match(token.Fun):
+		return p.lambda()
 	case p.match(token.False):
 		return p.alg.Literal(false), nil
 	case p.match(token.True):
@@ -572,6 +750,10 @@ func (p *Parser[E, S]) primary() (E, error) {
 		return p.alg.Literal(nil), nil
 	case p.match(token.Number, token.String):
 		return p.alg.Literal(p.previous().Literal), nil
+	case p.match(token.LeftBracket):
+		return p.list()
+	case p.match(token.LeftBrace):
+		return p.hashMap()
 	case p.match(token.LeftParen):
 		expr, err := p.expression()
 		if err != nil {
@@ -586,7 +768,72 @@ func (p *Parser[E, S]) primary() (E, error) {
 		return p.alg.Grouping(expr), nil
 	}
 
-	return p.alg.Literal(nil), fmt.Errorf("Unexpected token %s at %d", p.peek().Lexeme, p.peek().Line)
+	return p.alg.Literal(nil), p.error(p.peek(), "expected expression.")
+}
+
+func (p *Parser[E, S]) list() (E, error) {
+	bracket := p.previous()
+
+	var elements []E
+	if !p.check(token.RightBracket) {
+		for {
+			expr, err := p.expression()
+			if err != nil {
+				return p.alg.NilExpr(), err
+			}
+
+			elements = append(elements, expr)
+
+			if !p.match(token.Comma) {
+				break
+			}
+		}
+	}
+
+	_, err := p.consume(token.RightBracket, "expected ']' after list elements.")
+	if err != nil {
+		return p.alg.NilExpr(), err
+	}
+
+	return p.alg.List(bracket, elements), nil
+}
+
+func (p *Parser[E, S]) hashMap() (E, error) {
+	brace := p.previous()
+
+	var keys, values []E
+	if !p.check(token.RightBrace) {
+		for {
+			key, err := p.expression()
+			if err != nil {
+				return p.alg.NilExpr(), err
+			}
+
+			_, err = p.consume(token.Colon, "expected ':' after map key.")
+			if err != nil {
+				return p.alg.NilExpr(), err
+			}
+
+			value, err := p.expression()
+			if err != nil {
+				return p.alg.NilExpr(), err
+			}
+
+			keys = append(keys, key)
+			values = append(values, value)
+
+			if !p.match(token.Comma) {
+				break
+			}
+		}
+	}
+
+	_, err := p.consume(token.RightBrace, "expected '}' after map entries.")
+	if err != nil {
+		return p.alg.NilExpr(), err
+	}
+
+	return p.alg.Map(brace, keys, values), nil
 }
 
 // synchronize method moves cursor
@@ -600,7 +847,7 @@ func (p *Parser[E, S]) synchronize() {
 		}
 
 		switch p.peek().Kind {
-		case token.Class, token.Fun, token.Var, token.For, token.If, token.While, token.Return:
+		case token.Class, token.Fun, token.Var, token.For, token.If, token.While, token.Return, token.Break, token.Continue:
 			return
 		}
 
@@ -613,7 +860,11 @@ func (p *Parser[E, S]) consume(kind token.Kind, message string) (token.Token, er
 		return p.advance(), nil
 	}
 
-	return token.NilV, errors.New(message)
+	return token.NilV, p.error(p.peek(), message)
+}
+
+func (p *Parser[E, S]) error(tok token.Token, message string) error {
+	return Error{Token: tok, Message: message}
 }
 
 func (p *Parser[E, S]) match(kinds ...token.Kind) bool {
@@ -633,6 +884,15 @@ func (p *Parser[E, S]) check(kind token.Kind) bool {
 	return p.peek().Kind == kind
 }
 
+// checkNext is check for the token after the current one
+func (p *Parser[E, S]) checkNext(kind token.Kind) bool {
+	if p.isAtEnd() {
+		return false
+	}
+
+	return p.tokens[p.current+1].Kind == kind
+}
+
 func (p *Parser[E, S]) advance() token.Token {
 	if !p.isAtEnd() {
 		p.current++
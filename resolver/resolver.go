@@ -0,0 +1,459 @@
+package resolver
+
+import (
+	"fmt"
+
+	interp "github.com/havrydotdev/golox/interpreter"
+	"github.com/havrydotdev/golox/token"
+)
+
+type functionKind int
+
+const (
+	noFunction functionKind = iota
+	function
+	method
+	initializer
+)
+
+type classKind int
+
+const (
+	noClass classKind = iota
+	class
+	subclass
+)
+
+type Error struct {
+	Token   token.Token
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("[line %d] Error at '%s': %s", e.Token.Line, e.Token.Lexeme, e.Message)
+}
+
+// Node pairs the value built by the wrapped algebra
+// with its deferred resolution, which can only run
+// once the whole program has been parsed
+type Node[T any] struct {
+	Value T
+
+	resolve func()
+	// only set for function declarations,
+	// classes use it to resolve their methods
+	decl *declaration
+}
+
+type declaration struct {
+	name    token.Token
+	resolve func(kind functionKind)
+}
+
+func (n Node[T]) run() {
+	if n.resolve != nil {
+		n.resolve()
+	}
+}
+
+// Resolver is an algebra that wraps another one and statically
+// resolves every variable to the scope it is declared in
+//
+// if the wrapped algebra implements interp.Resolvable
+// it is told the depth of every resolved expression
+type Resolver[E any, S any] struct {
+	alg    interp.Alg[E, S]
+	scopes []map[string]bool
+	errors []error
+
+	function functionKind
+	class    classKind
+	// number of loops around the current statement
+	loops int
+}
+
+func New[E any, S any](alg interp.Alg[E, S]) *Resolver[E, S] {
+	return &Resolver[E, S]{alg: alg}
+}
+
+// Resolve runs the resolution of the parsed statements and
+// returns the values built by the wrapped algebra
+func (r *Resolver[E, S]) Resolve(stmts []Node[S]) ([]S, []error) {
+	r.errors = nil
+
+	for _, stmt := range stmts {
+		stmt.run()
+	}
+
+	return values(stmts), r.errors
+}
+
+// ResolveExpression resolves a single expression
+// parsed outside of any statement
+func (r *Resolver[E, S]) ResolveExpression(expr Node[E]) (E, []error) {
+	r.errors = nil
+	expr.run()
+
+	return expr.Value, r.errors
+}
+
+func (r *Resolver[E, S]) Grouping(expr Node[E]) Node[E] {
+	return Node[E]{Value: r.alg.Grouping(expr.Value), resolve: expr.run}
+}
+
+func (r *Resolver[E, S]) Literal(value any) Node[E] {
+	return Node[E]{Value: r.alg.Literal(value)}
+}
+
+func (r *Resolver[E, S]) This(keyword token.Token) Node[E] {
+	value := r.alg.This(keyword)
+
+	return Node[E]{Value: value, resolve: func() {
+		if r.class == noClass {
+			r.error(keyword, "can't use 'this' outside of a class.")
+			return
+		}
+
+		r.resolveLocal(value, keyword)
+	}}
+}
+
+func (r *Resolver[E, S]) Super(keyword token.Token, method token.Token) Node[E] {
+	value := r.alg.Super(keyword, method)
+
+	return Node[E]{Value: value, resolve: func() {
+		switch r.class {
+		case noClass:
+			r.error(keyword, "can't use 'super' outside of a class.")
+			return
+		case class:
+			r.error(keyword, "can't use 'super' in a class with no superclass.")
+			return
+		}
+
+		r.resolveLocal(value, keyword)
+	}}
+}
+
+func (r *Resolver[E, S]) Variable(name token.Token) Node[E] {
+	value := r.alg.Variable(name)
+
+	return Node[E]{Value: value, resolve: func() {
+		if len(r.scopes) != 0 {
+			defined, declared := r.scopes[len(r.scopes)-1][name.Lexeme]
+			if declared && !defined {
+				r.error(name, "can't read local variable in its own initializer.")
+			}
+		}
+
+		r.resolveLocal(value, name)
+	}}
+}
+
+func (r *Resolver[E, S]) Get(name token.Token, expr Node[E]) Node[E] {
+	return Node[E]{Value: r.alg.Get(name, expr.Value), resolve: expr.run}
+}
+
+func (r *Resolver[E, S]) Unary(op token.Token, right Node[E]) Node[E] {
+	return Node[E]{Value: r.alg.Unary(op, right.Value), resolve: right.run}
+}
+
+func (r *Resolver[E, S]) Assign(name token.Token, value Node[E]) Node[E] {
+	assign := r.alg.Assign(name, value.Value)
+
+	return Node[E]{Value: assign, resolve: func() {
+		value.run()
+		r.resolveLocal(assign, name)
+	}}
+}
+
+func (r *Resolver[E, S]) Binary(op token.Token, left, right Node[E]) Node[E] {
+	return Node[E]{Value: r.alg.Binary(op, left.Value, right.Value), resolve: func() {
+		left.run()
+		right.run()
+	}}
+}
+
+func (r *Resolver[E, S]) Logical(op token.Token, left, right Node[E]) Node[E] {
+	return Node[E]{Value: r.alg.Logical(op, left.Value, right.Value), resolve: func() {
+		left.run()
+		right.run()
+	}}
+}
+
+func (r *Resolver[E, S]) Call(callee Node[E], paren token.Token, args []Node[E]) Node[E] {
+	return Node[E]{Value: r.alg.Call(callee.Value, paren, values(args)), resolve: func() {
+		callee.run()
+		for _, arg := range args {
+			arg.run()
+		}
+	}}
+}
+
+func (r *Resolver[E, S]) Set(object Node[E], name token.Token, value Node[E]) Node[E] {
+	return Node[E]{Value: r.alg.Set(object.Value, name, value.Value), resolve: func() {
+		value.run()
+		object.run()
+	}}
+}
+
+func (r *Resolver[E, S]) List(bracket token.Token, elements []Node[E]) Node[E] {
+	return Node[E]{Value: r.alg.List(bracket, values(elements)), resolve: func() {
+		for _, element := range elements {
+			element.run()
+		}
+	}}
+}
+
+func (r *Resolver[E, S]) Map(brace token.Token, keys []Node[E], vals []Node[E]) Node[E] {
+	return Node[E]{Value: r.alg.Map(brace, values(keys), values(vals)), resolve: func() {
+		for i := range keys {
+			keys[i].run()
+			vals[i].run()
+		}
+	}}
+}
+
+func (r *Resolver[E, S]) Index(object Node[E], bracket token.Token, index Node[E]) Node[E] {
+	return Node[E]{Value: r.alg.Index(object.Value, bracket, index.Value), resolve: func() {
+		object.run()
+		index.run()
+	}}
+}
+
+func (r *Resolver[E, S]) SetIndex(object Node[E], bracket token.Token, index Node[E], value Node[E]) Node[E] {
+	return Node[E]{Value: r.alg.SetIndex(object.Value, bracket, index.Value, value.Value), resolve: func() {
+		object.run()
+		index.run()
+		value.run()
+	}}
+}
+
+func (r *Resolver[E, S]) Block(stmts []Node[S]) Node[S] {
+	return Node[S]{Value: r.alg.Block(values(stmts)), resolve: func() {
+		r.beginScope()
+		for _, stmt := range stmts {
+			stmt.run()
+		}
+		r.endScope()
+	}}
+}
+
+func (r *Resolver[E, S]) While(cond Node[E], body Node[S]) Node[S] {
+	return Node[S]{Value: r.alg.While(cond.Value, body.Value), resolve: func() {
+		cond.run()
+		r.loop(body)
+	}}
+}
+
+func (r *Resolver[E, S]) For(init Node[S], cond Node[E], incr Node[E], body Node[S]) Node[S] {
+	return Node[S]{Value: r.alg.For(init.Value, cond.Value, incr.Value, body.Value), resolve: func() {
+		r.beginScope()
+		init.run()
+		cond.run()
+		r.loop(body)
+		incr.run()
+		r.endScope()
+	}}
+}
+
+func (r *Resolver[E, S]) Break(keyword token.Token) Node[S] {
+	return Node[S]{Value: r.alg.Break(keyword), resolve: func() {
+		if r.loops == 0 {
+			r.error(keyword, "can't use 'break' outside of a loop.")
+		}
+	}}
+}
+
+func (r *Resolver[E, S]) Continue(keyword token.Token) Node[S] {
+	return Node[S]{Value: r.alg.Continue(keyword), resolve: func() {
+		if r.loops == 0 {
+			r.error(keyword, "can't use 'continue' outside of a loop.")
+		}
+	}}
+}
+
+func (r *Resolver[E, S]) ExprStatement(expr Node[E]) Node[S] {
+	return Node[S]{Value: r.alg.ExprStatement(expr.Value), resolve: expr.run}
+}
+
+func (r *Resolver[E, S]) If(cond Node[E], then Node[S], _else Node[S]) Node[S] {
+	return Node[S]{Value: r.alg.If(cond.Value, then.Value, _else.Value), resolve: func() {
+		cond.run()
+		then.run()
+		_else.run()
+	}}
+}
+
+func (r *Resolver[E, S]) Var(name token.Token, init Node[E]) Node[S] {
+	return Node[S]{Value: r.alg.Var(name, init.Value), resolve: func() {
+		r.declare(name)
+		init.run()
+		r.define(name)
+	}}
+}
+
+func (r *Resolver[E, S]) Return(keyword token.Token, value Node[E]) Node[S] {
+	return Node[S]{Value: r.alg.Return(keyword, value.Value), resolve: func() {
+		if r.function == noFunction {
+			r.error(keyword, "can't return from top-level code.")
+		}
+
+		value.run()
+	}}
+}
+
+func (r *Resolver[E, S]) Class(name token.Token, superclass Node[E], methods []Node[S]) Node[S] {
+	return Node[S]{Value: r.alg.Class(name, superclass.Value, values(methods)), resolve: func() {
+		enclosing := r.class
+		r.class = class
+		defer func() { r.class = enclosing }()
+
+		r.declare(name)
+		r.define(name)
+
+		if superclass.resolve != nil {
+			r.class = subclass
+			superclass.run()
+
+			r.beginScope()
+			r.scopes[len(r.scopes)-1]["super"] = true
+			defer r.endScope()
+		}
+
+		r.beginScope()
+		r.scopes[len(r.scopes)-1]["this"] = true
+
+		for _, m := range methods {
+			if m.decl == nil {
+				continue
+			}
+
+			kind := method
+			if m.decl.name.Lexeme == "init" {
+				kind = initializer
+			}
+
+			m.decl.resolve(kind)
+		}
+
+		r.endScope()
+	}}
+}
+
+func (r *Resolver[E, S]) Function(name token.Token, params []token.Token, body []Node[S]) Node[S] {
+	decl := &declaration{name, func(kind functionKind) {
+		r.resolveFunction(params, body, kind)
+	}}
+
+	return Node[S]{Value: r.alg.Function(name, params, values(body)), decl: decl, resolve: func() {
+		r.declare(name)
+		r.define(name)
+		decl.resolve(function)
+	}}
+}
+
+func (r *Resolver[E, S]) Lambda(keyword token.Token, params []token.Token, body []Node[S]) Node[E] {
+	return Node[E]{Value: r.alg.Lambda(keyword, params, values(body)), resolve: func() {
+		r.resolveFunction(params, body, function)
+	}}
+}
+
+// At implements interp.Positioned for the
+// wrapped algebra if it implements it itself
+func (r *Resolver[E, S]) At(start token.Token, stmt Node[S]) Node[S] {
+	if alg, ok := r.alg.(interp.Positioned[S]); ok {
+		stmt.Value = alg.At(start, stmt.Value)
+	}
+
+	return stmt
+}
+
+func (r *Resolver[E, S]) NilExpr() Node[E] {
+	return Node[E]{Value: r.alg.NilExpr()}
+}
+
+func (r *Resolver[E, S]) NilStmt() Node[S] {
+	return Node[S]{Value: r.alg.NilStmt()}
+}
+
+func (r *Resolver[E, S]) loop(body Node[S]) {
+	r.loops++
+	body.run()
+	r.loops--
+}
+
+func (r *Resolver[E, S]) resolveFunction(params []token.Token, body []Node[S], kind functionKind) {
+	enclosing, loops := r.function, r.loops
+	r.function, r.loops = kind, 0
+	defer func() { r.function, r.loops = enclosing, loops }()
+
+	r.beginScope()
+	for _, param := range params {
+		r.declare(param)
+		r.define(param)
+	}
+
+	for _, stmt := range body {
+		stmt.run()
+	}
+	r.endScope()
+}
+
+// resolveLocal finds the innermost scope where the name is declared,
+// names which are not found in any scope are global
+func (r *Resolver[E, S]) resolveLocal(expr E, name token.Token) {
+	depth := len(r.scopes)
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if _, ok := r.scopes[i][name.Lexeme]; ok {
+			depth = len(r.scopes) - 1 - i
+			break
+		}
+	}
+
+	if alg, ok := r.alg.(interp.Resolvable[E]); ok {
+		alg.Resolve(expr, depth)
+	}
+}
+
+func (r *Resolver[E, S]) declare(name token.Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+
+	scope := r.scopes[len(r.scopes)-1]
+	if _, ok := scope[name.Lexeme]; ok {
+		r.error(name, "already a variable with this name in this scope.")
+	}
+
+	scope[name.Lexeme] = false
+}
+
+func (r *Resolver[E, S]) define(name token.Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+
+	r.scopes[len(r.scopes)-1][name.Lexeme] = true
+}
+
+func (r *Resolver[E, S]) beginScope() {
+	r.scopes = append(r.scopes, make(map[string]bool))
+}
+
+func (r *Resolver[E, S]) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *Resolver[E, S]) error(name token.Token, message string) {
+	r.errors = append(r.errors, Error{name, message})
+}
+
+func values[T any](nodes []Node[T]) []T {
+	vals := make([]T, len(nodes))
+	for i, node := range nodes {
+		vals[i] = node.Value
+	}
+
+	return vals
+}
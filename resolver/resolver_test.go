@@ -0,0 +1,85 @@
+package resolver
+
+import (
+	"testing"
+
+	eval "github.com/havrydotdev/golox/evaluator"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/scanner"
+)
+
+func resolve(t *testing.T, source string) []error {
+	t.Helper()
+
+	tokens, errs := scanner.New(source).Scan()
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	res := New(eval.New())
+	nodes, errs := parser.New(tokens, res).Parse()
+	for _, err := range errs {
+		t.Fatal(err)
+	}
+
+	_, errs = res.Resolve(nodes)
+	return errs
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := map[string]string{
+		"own initializer":   "{ var a = 1; { var a = a; } }",
+		"top level return":  "return 1;",
+		"duplicate local":   "fun f() { var a = 1; var a = 2; }",
+		"duplicate param":   "fun f(a, a) {}",
+		"this outside":      "print(this);",
+		"super outside":     "fun f() { super.f(); }",
+		"super no subclass": "class A { f() { super.f(); } }",
+		"break outside":     "break;",
+		"continue in fun":   "while (true) { fun f() { continue; } }",
+		"break in lambda":   "while (true) { var f = fun () { break; }; }",
+		"lambda param":      "var f = fun (a, a) {};",
+	}
+
+	for name, source := range tests {
+		t.Run(name, func(t *testing.T) {
+			errs := resolve(t, source)
+			if len(errs) != 1 {
+				t.Errorf("expected 1 error, got %v", errs)
+			}
+		})
+	}
+}
+
+func TestResolveValid(t *testing.T) {
+	source := `
+var a = 1;
+var a = a;
+
+class A {
+  init() { return; }
+  f() { return this; }
+}
+
+class B < A {
+  f() { return super.f(); }
+}
+
+for (var i = 0; i < 1; i = i + 1) {
+  while (true) { break; }
+  continue;
+}
+
+fun outer() {
+  var a = 1;
+  fun inner() { return a; }
+  return inner;
+}
+
+var lambda = fun (a) { return fun () { return a; }; };
+`
+
+	if errs := resolve(t, source); len(errs) != 0 {
+		t.Error(errs)
+	}
+}
@@ -3,19 +3,21 @@ package scanner
 import "github.com/havrydotdev/golox/token"
 
 var keywords = map[string]token.Kind{
-	"and":    token.And,
-	"class":  token.Class,
-	"else":   token.Else,
-	"false":  token.False,
-	"for":    token.For,
-	"fun":    token.Fun,
-	"if":     token.If,
-	"nil":    token.Nil,
-	"or":     token.Or,
-	"return": token.Return,
-	"super":  token.Super,
-	"this":   token.This,
-	"true":   token.True,
-	"var":    token.Var,
-	"while":  token.While,
+	"and":      token.And,
+	"break":    token.Break,
+	"class":    token.Class,
+	"continue": token.Continue,
+	"else":     token.Else,
+	"false":    token.False,
+	"for":      token.For,
+	"fun":      token.Fun,
+	"if":       token.If,
+	"nil":      token.Nil,
+	"or":       token.Or,
+	"return":   token.Return,
+	"super":    token.Super,
+	"this":     token.This,
+	"true":     token.True,
+	"var":      token.Var,
+	"while":    token.While,
 }
@@ -1,42 +1,89 @@
 package scanner
 
 import (
-	"errors"
 	"fmt"
 	"strconv"
+	"unicode/utf8"
 
 	"github.com/havrydotdev/golox/token"
 )
 
+type Error struct {
+	Token   token.Token
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("[line %d:%d] Error: %s", e.Token.Line, e.Token.Column, e.Message)
+}
+
 type Scanner struct {
 	source string
+	file   string
 	tokens []token.Token
+	errors []error
+
+	keepComments bool
+	comments     []token.Token
 
 	start   int
 	current int
 	line    int
+	// offset of the first character of the current line
+	lineStart int
+
+	// position of the token which is being scanned
+	startLine   int
+	startColumn int
 }
 
 func New(source string) *Scanner {
 	return &Scanner{source: source, tokens: make([]token.Token, 0), start: 0, current: 0, line: 1}
 }
 
-func (s *Scanner) Scan() ([]token.Token, error) {
+// NewFile creates a scanner whose tokens
+// remember the file they come from
+func NewFile(file, source string) *Scanner {
+	s := New(source)
+	s.file = file
+
+	return s
+}
+
+// KeepComments makes the scanner collect comments,
+// they are not part of the tokens, see Comments
+func (s *Scanner) KeepComments() *Scanner {
+	s.keepComments = true
+	return s
+}
+
+// Comments returns the Comment tokens found by Scan in the
+// order they appear, it is empty unless KeepComments was called
+func (s *Scanner) Comments() []token.Token {
+	return s.comments
+}
+
+// Scan reads all of the tokens, lexical errors don't stop it,
+// instead every one of them is reported and an Error token
+// is put in place of the lexeme which caused it
+func (s *Scanner) Scan() ([]token.Token, []error) {
 	for !s.isAtEnd() {
 		s.start = s.current
+		s.startLine = s.line
+		s.startColumn = s.start - s.lineStart + 1
 
-		if err := s.scanToken(); err != nil {
-			return nil, err
-		}
+		s.scanToken()
 	}
 
-	eof := token.New(token.Eof, "", nil, s.line)
-	s.tokens = append(s.tokens, eof)
+	s.start = s.current
+	s.startLine = s.line
+	s.startColumn = s.start - s.lineStart + 1
+	s.addToken(token.Eof)
 
-	return s.tokens, nil
+	return s.tokens, s.errors
 }
 
-func (s *Scanner) scanToken() error {
+func (s *Scanner) scanToken() {
 	c := s.advance()
 
 	switch c {
@@ -49,8 +96,14 @@ func (s *Scanner) scanToken() error {
 		s.addToken(token.LeftBrace)
 	case '}':
 		s.addToken(token.RightBrace)
+	case '[':
+		s.addToken(token.LeftBracket)
+	case ']':
+		s.addToken(token.RightBracket)
 	case ',':
 		s.addToken(token.Comma)
+	case ':':
+		s.addToken(token.Colon)
 	case '.':
 		s.addToken(token.Dot)
 	case '-':
@@ -98,13 +151,11 @@ func (s *Scanner) scanToken() error {
 			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+
+			s.addComment()
 		} else if s.match('*') {
 			// multi-line comment
 			for !s.isAtEnd() {
-				if s.peek() == '\n' {
-					s.line++
-				}
-
 				if s.peek() == '*' && s.peekNext() == '/' {
 					// eat comment
 					s.advance()
@@ -112,8 +163,12 @@ func (s *Scanner) scanToken() error {
 					break
 				}
 
-				s.advance()
+				if s.advance() == '\n' {
+					s.newline()
+				}
 			}
+
+			s.addComment()
 		} else {
 			s.addToken(token.Slash)
 		}
@@ -123,13 +178,11 @@ func (s *Scanner) scanToken() error {
 		break
 
 	case '\n':
-		s.line++
+		s.newline()
 
 	// literals
 	case '"':
-		if err := s.string(); err != nil {
-			return err
-		}
+		s.string()
 
 	// keywords
 
@@ -140,11 +193,12 @@ func (s *Scanner) scanToken() error {
 		} else if isAlpha(c) {
 			s.identifier()
 		} else {
-			return fmt.Errorf("Unknown token %s at %d, %d", string(c), s.current, s.line)
+			// report the whole character, not just its first byte
+			r, size := utf8.DecodeRuneInString(s.source[s.start:])
+			s.current = s.start + size
+			s.error(fmt.Sprintf("unexpected character %q.", r))
 		}
 	}
-
-	return nil
 }
 
 func isAlpha(c byte) bool {
@@ -193,23 +247,20 @@ func (s *Scanner) number() {
 	s.addToken(token.Number, float32(num))
 }
 
-func (s *Scanner) string() error {
+func (s *Scanner) string() {
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
-			s.line++
+		if s.advance() == '\n' {
+			s.newline()
 		}
-
-		s.advance()
 	}
 
 	if s.isAtEnd() {
-		return errors.New("Unterminated string.")
+		s.error("unterminated string.")
+		return
 	}
 
 	s.advance()
 	s.addToken(token.String, s.source[s.start+1:s.current-1])
-
-	return nil
 }
 
 func (s *Scanner) addToken(kind token.Kind, literal ...any) {
@@ -218,9 +269,39 @@ func (s *Scanner) addToken(kind token.Kind, literal ...any) {
 		l = literal[0]
 	}
 
-	lexeme := s.source[s.start:s.current]
+	s.tokens = append(s.tokens, token.Token{
+		Kind:    kind,
+		Lexeme:  s.source[s.start:s.current],
+		Literal: l,
+		Line:    s.startLine,
+		Column:  s.startColumn,
+		Start:   s.start,
+		End:     s.current,
+		File:    s.file,
+	})
+}
+
+func (s *Scanner) addComment() {
+	if !s.keepComments {
+		return
+	}
+
+	// addToken is reused so comments get their positions the same way
+	s.addToken(token.Comment)
+	s.comments = append(s.comments, s.tokens[len(s.tokens)-1])
+	s.tokens = s.tokens[:len(s.tokens)-1]
+}
+
+// error reports the current lexeme and adds it as an Error token
+func (s *Scanner) error(message string) {
+	s.addToken(token.Error)
+	s.errors = append(s.errors, Error{s.tokens[len(s.tokens)-1], message})
+}
 
-	s.tokens = append(s.tokens, token.New(kind, lexeme, l, s.line))
+// newline must be called after a '\n' is consumed
+func (s *Scanner) newline() {
+	s.line++
+	s.lineStart = s.current
 }
 
 func (s *Scanner) match(expected byte) bool {
@@ -1,7 +1,10 @@
 package scanner
 
 import (
+	"slices"
 	"testing"
+
+	"github.com/havrydotdev/golox/token"
 )
 
 const (
@@ -9,12 +12,77 @@ const (
 )
 
 func TestBasic(t *testing.T) {
-	tokens, err := New(TestBasicInput).Scan()
-	if err != nil {
-		t.Errorf("Scanning failed: %s\n", err.Error())
+	tokens, errs := New(TestBasicInput).Scan()
+	if len(errs) != 0 {
+		t.Errorf("Scanning failed: %v\n", errs)
 	}
 
 	for _, token := range tokens {
 		t.Log(token)
 	}
 }
+
+func TestPositions(t *testing.T) {
+	source := "var a = \"x\ny\";\n  /* a\n */ print(a);"
+	tokens, errs := NewFile("pos.lox", source).Scan()
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	expected := []struct {
+		kind                     token.Kind
+		line, column, start, end int
+	}{
+		{token.Var, 1, 1, 0, 3},
+		{token.Identifier, 1, 5, 4, 5},
+		{token.Equal, 1, 7, 6, 7},
+		{token.String, 1, 9, 8, 13},
+		{token.Semicolon, 2, 3, 13, 14},
+		{token.Identifier, 4, 5, 26, 31},
+		{token.LeftParen, 4, 10, 31, 32},
+		{token.Identifier, 4, 11, 32, 33},
+		{token.RightParen, 4, 12, 33, 34},
+		{token.Semicolon, 4, 13, 34, 35},
+		{token.Eof, 4, 14, 35, 35},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %v", len(expected), tokens)
+	}
+
+	for i, tok := range tokens {
+		e := expected[i]
+		if tok.Kind != e.kind || tok.Line != e.line || tok.Column != e.column ||
+			tok.Start != e.start || tok.End != e.end || tok.File != "pos.lox" {
+			t.Errorf("token %d: expected %+v, got %+v", i, e, tok)
+		}
+
+		if tok.Lexeme != source[tok.Start:tok.End] {
+			t.Errorf("token %d: lexeme %q doesn't match its span", i, tok.Lexeme)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tokens, errs := New("var a = 1 @ 2;\nvar é = \"abc;").Scan()
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errs)
+	}
+
+	var kinds []token.Kind
+	for _, tok := range tokens {
+		kinds = append(kinds, tok.Kind)
+	}
+
+	expected := []token.Kind{
+		token.Var, token.Identifier, token.Equal, token.Number, token.Error, token.Number, token.Semicolon,
+		token.Var, token.Error, token.Equal, token.Error, token.Eof,
+	}
+	if !slices.Equal(kinds, expected) {
+		t.Errorf("expected kinds %v, got %v", expected, kinds)
+	}
+
+	if lexeme := errs[1].(Error).Token.Lexeme; lexeme != "é" {
+		t.Errorf("expected the whole character to be reported, got %q", lexeme)
+	}
+}
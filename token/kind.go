@@ -1,5 +1,7 @@
 package token
 
+import "fmt"
+
 type Kind int
 
 const (
@@ -8,7 +10,10 @@ const (
 	RightParen
 	LeftBrace
 	RightBrace
+	LeftBracket
+	RightBracket
 	Comma
+	Colon
 	Dot
 	Minus
 	Plus
@@ -33,7 +38,9 @@ const (
 
 	// Keywords
 	And
+	Break
 	Class
+	Continue
 	Else
 	False
 	Fun
@@ -48,5 +55,65 @@ const (
 	Var
 	While
 
+	// Error is a lexeme the scanner couldn't make sense of
+	Error
+	// Comment tokens are only kept on request, see Scanner.KeepComments
+	Comment
 	Eof
 )
+
+var kindNames = [...]string{
+	LeftParen:    "LeftParen",
+	RightParen:   "RightParen",
+	LeftBrace:    "LeftBrace",
+	RightBrace:   "RightBrace",
+	LeftBracket:  "LeftBracket",
+	RightBracket: "RightBracket",
+	Comma:        "Comma",
+	Colon:        "Colon",
+	Dot:          "Dot",
+	Minus:        "Minus",
+	Plus:         "Plus",
+	Semicolon:    "Semicolon",
+	Slash:        "Slash",
+	Star:         "Star",
+	Bang:         "Bang",
+	BangEqual:    "BangEqual",
+	Equal:        "Equal",
+	EqualEqual:   "EqualEqual",
+	Greater:      "Greater",
+	GreaterEqual: "GreaterEqual",
+	Less:         "Less",
+	LessEqual:    "LessEqual",
+	Identifier:   "Identifier",
+	String:       "String",
+	Number:       "Number",
+	And:          "And",
+	Break:        "Break",
+	Class:        "Class",
+	Continue:     "Continue",
+	Else:         "Else",
+	False:        "False",
+	Fun:          "Fun",
+	For:          "For",
+	If:           "If",
+	Nil:          "Nil",
+	Or:           "Or",
+	Return:       "Return",
+	Super:        "Super",
+	This:         "This",
+	True:         "True",
+	Var:          "Var",
+	While:        "While",
+	Error:        "Error",
+	Comment:      "Comment",
+	Eof:          "Eof",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+
+	return kindNames[k]
+}
@@ -11,10 +11,28 @@ type Token struct {
 	Lexeme  string
 	Literal any
 	Line    int
+	// Column is the byte column of the first character,
+	// it starts at 1 the same way Line does
+	Column int
+	// Start and End are byte offsets of the
+	// lexeme in the source, End is exclusive
+	Start int
+	End   int
+	// File is empty when the source didn't come from a file
+	File string
 }
 
 func New(kind Kind, lexeme string, literal any, line int) Token {
-	return Token{kind, lexeme, literal, line}
+	return Token{Kind: kind, Lexeme: lexeme, Literal: literal, Line: line}
+}
+
+// Pos formats the position of the token as file:line:column
+func (t Token) Pos() string {
+	if t.File == "" {
+		return fmt.Sprintf("%d:%d", t.Line, t.Column)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", t.File, t.Line, t.Column)
 }
 
 func (t Token) String() string {
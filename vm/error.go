@@ -0,0 +1,25 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/havrydotdev/golox/lox"
+	"github.com/havrydotdev/golox/token"
+)
+
+// RuntimeError is an error raised by the running code, Token is
+// the one of the failed instruction and Frames is the call stack,
+// innermost call first
+type RuntimeError struct {
+	Token   token.Token
+	Message string
+	Frames  []lox.Frame
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("[line %d] %s", e.Token.Line, e.Message)
+}
+
+func (e *RuntimeError) Trace() string {
+	return lox.Trace(e.Frames)
+}
@@ -0,0 +1,14 @@
+package vm
+
+import "github.com/havrydotdev/golox/lox"
+
+func newGlobals() map[string]any {
+	globals := make(map[string]any, len(lox.Natives))
+	for _, native := range lox.Natives {
+		globals[native.Name] = &Native{native.Name, uint8(len(native.Params)), func(vm *VM, args []any) (any, error) {
+			return native.Call(vm, args)
+		}}
+	}
+
+	return globals
+}
@@ -0,0 +1,77 @@
+package vm
+
+import (
+	"github.com/havrydotdev/golox/compiler"
+)
+
+type Closure struct {
+	Function *compiler.Function
+	upvalues []*Upvalue
+}
+
+func (c *Closure) String() string {
+	return c.Function.String()
+}
+
+// Upvalue points at a stack slot while the variable
+// is still on the stack and holds its value once closed
+type Upvalue struct {
+	slot   int
+	closed any
+	next   *Upvalue
+}
+
+type Native struct {
+	Name  string
+	Arity uint8
+	fn    func(vm *VM, args []any) (any, error)
+}
+
+func (n *Native) String() string {
+	return "<native fn>"
+}
+
+type Class struct {
+	Name    string
+	methods map[string]*Closure
+}
+
+func (c *Class) String() string {
+	return c.Name
+}
+
+type Instance struct {
+	Class  *Class
+	fields map[string]any
+}
+
+func (i *Instance) String() string {
+	return i.Class.Name + " instance"
+}
+
+type BoundMethod struct {
+	receiver any
+	method   *Closure
+}
+
+func (b *BoundMethod) String() string {
+	return b.method.String()
+}
+
+func isTruthy(value any) bool {
+	if value == nil {
+		return false
+	}
+
+	if val, ok := value.(bool); ok {
+		return val
+	}
+
+	return true
+}
+
+// every value of the vm is comparable, so
+// equality is the equality of go interfaces
+func isEqual(left, right any) bool {
+	return left == right
+}
@@ -0,0 +1,524 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/havrydotdev/golox/compiler"
+	"github.com/havrydotdev/golox/lox"
+	"github.com/havrydotdev/golox/token"
+)
+
+const (
+	framesMax = 256
+	stackMax  = framesMax * 256
+)
+
+type frame struct {
+	closure *Closure
+	ip      int
+	// first stack slot of the frame
+	base int
+}
+
+type VM struct {
+	frames     [framesMax]frame
+	frameCount int
+
+	stack [stackMax]any
+	sp    int
+
+	globals      map[string]any
+	openUpvalues *Upvalue
+
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+func New() *VM {
+	return &VM{globals: newGlobals(), stdin: os.Stdin, stdout: os.Stdout}
+}
+
+// SetStdin sets what natives read from, it is os.Stdin by default
+func (vm *VM) SetStdin(r io.Reader) {
+	vm.stdin = r
+}
+
+func (vm *VM) Stdin() io.Reader {
+	return vm.stdin
+}
+
+// SetStdout sets where print and other natives
+// write to, it is os.Stdout by default
+func (vm *VM) SetStdout(w io.Writer) {
+	vm.stdout = w
+}
+
+func (vm *VM) Stdout() io.Writer {
+	return vm.stdout
+}
+
+// Run executes the compiled script and returns
+// its result, globals are kept between the runs
+func (vm *VM) Run(script *compiler.Function) (any, error) {
+	vm.sp = 0
+	vm.frameCount = 0
+	vm.openUpvalues = nil
+
+	closure := &Closure{Function: script}
+	if err := vm.push(closure); err != nil {
+		return nil, err
+	}
+
+	if err := vm.call(closure, 0); err != nil {
+		return nil, err
+	}
+
+	return vm.run()
+}
+
+func (vm *VM) run() (any, error) {
+	frame := &vm.frames[vm.frameCount-1]
+	chunk := &frame.closure.Function.Chunk
+
+	readByte := func() byte {
+		b := chunk.Code[frame.ip]
+		frame.ip++
+		return b
+	}
+
+	readShort := func() uint16 {
+		frame.ip += 2
+		return uint16(chunk.Code[frame.ip-2])<<8 | uint16(chunk.Code[frame.ip-1])
+	}
+
+	readString := func() string {
+		return chunk.Constants[readShort()].(string)
+	}
+
+	for {
+		switch op := compiler.OpCode(readByte()); op {
+		case compiler.OpConstant:
+			if err := vm.push(chunk.Constants[readShort()]); err != nil {
+				return nil, err
+			}
+		case compiler.OpNil:
+			if err := vm.push(nil); err != nil {
+				return nil, err
+			}
+		case compiler.OpTrue:
+			if err := vm.push(true); err != nil {
+				return nil, err
+			}
+		case compiler.OpFalse:
+			if err := vm.push(false); err != nil {
+				return nil, err
+			}
+		case compiler.OpPop:
+			vm.sp--
+
+		case compiler.OpGetLocal:
+			if err := vm.push(vm.stack[frame.base+int(readByte())]); err != nil {
+				return nil, err
+			}
+		case compiler.OpSetLocal:
+			vm.stack[frame.base+int(readByte())] = vm.peek(0)
+		case compiler.OpGetGlobal:
+			name := readString()
+			val, ok := vm.globals[name]
+			if !ok {
+				return nil, vm.runtimeError("undefined variable %s", name)
+			}
+
+			if err := vm.push(val); err != nil {
+				return nil, err
+			}
+		case compiler.OpDefineGlobal:
+			vm.globals[readString()] = vm.pop()
+		case compiler.OpSetGlobal:
+			name := readString()
+			if _, ok := vm.globals[name]; !ok {
+				return nil, vm.runtimeError("undefined variable %s", name)
+			}
+
+			vm.globals[name] = vm.peek(0)
+		case compiler.OpGetUpvalue:
+			up := frame.closure.upvalues[readByte()]
+			val := up.closed
+			if up.slot != -1 {
+				val = vm.stack[up.slot]
+			}
+
+			if err := vm.push(val); err != nil {
+				return nil, err
+			}
+		case compiler.OpSetUpvalue:
+			up := frame.closure.upvalues[readByte()]
+			if up.slot != -1 {
+				vm.stack[up.slot] = vm.peek(0)
+			} else {
+				up.closed = vm.peek(0)
+			}
+
+		case compiler.OpGetProperty:
+			inst, ok := vm.peek(0).(*Instance)
+			if !ok {
+				return nil, vm.runtimeError("only instances have properties.")
+			}
+
+			name := readString()
+			if val, ok := inst.fields[name]; ok {
+				vm.stack[vm.sp-1] = val
+				break
+			}
+
+			method, ok := inst.Class.methods[name]
+			if !ok {
+				return nil, vm.runtimeError("unknown key")
+			}
+
+			vm.stack[vm.sp-1] = &BoundMethod{inst, method}
+		case compiler.OpSetProperty:
+			inst, ok := vm.peek(1).(*Instance)
+			if !ok {
+				return nil, vm.runtimeError("only instances have fields")
+			}
+
+			val := vm.pop()
+			inst.fields[readString()] = val
+			vm.stack[vm.sp-1] = val
+		case compiler.OpGetSuper:
+			name := readString()
+			super := vm.pop().(*Class)
+
+			method, ok := super.methods[name]
+			if !ok {
+				return nil, vm.runtimeError("undefined property %s", name)
+			}
+
+			vm.stack[vm.sp-1] = &BoundMethod{vm.peek(0), method}
+
+		case compiler.OpEqual:
+			r := vm.pop()
+			vm.stack[vm.sp-1] = isEqual(vm.peek(0), r)
+		case compiler.OpGreater, compiler.OpGreaterEqual, compiler.OpLess, compiler.OpLessEqual,
+			compiler.OpSubtract, compiler.OpMultiply, compiler.OpDivide:
+			l, lok := vm.peek(1).(float32)
+			r, rok := vm.peek(0).(float32)
+			if !lok {
+				return nil, vm.runtimeError("Unexpected token %s", opLexemes[op])
+			} else if !rok {
+				return nil, vm.runtimeError("expected number, got %v", vm.peek(0))
+			}
+
+			vm.sp--
+			vm.stack[vm.sp-1] = arithmetic(op, l, r)
+		case compiler.OpAdd:
+			switch l := vm.peek(1).(type) {
+			case float32:
+				r, ok := vm.peek(0).(float32)
+				if !ok {
+					return nil, vm.runtimeError("expected number, got %v", vm.peek(0))
+				}
+
+				vm.sp--
+				vm.stack[vm.sp-1] = l + r
+			case string:
+				r, ok := vm.peek(0).(string)
+				if !ok {
+					return nil, vm.runtimeError("expected string, got %v", vm.peek(0))
+				}
+
+				vm.sp--
+				vm.stack[vm.sp-1] = l + r
+			default:
+				return nil, vm.runtimeError("Unexpected token +")
+			}
+		case compiler.OpNot:
+			vm.stack[vm.sp-1] = !isTruthy(vm.peek(0))
+		case compiler.OpNegate:
+			num, ok := vm.peek(0).(float32)
+			if !ok {
+				return nil, vm.runtimeError("Expected number, got %v", vm.peek(0))
+			}
+
+			vm.stack[vm.sp-1] = -num
+
+		case compiler.OpJump:
+			offset := readShort()
+			frame.ip += int(offset)
+		case compiler.OpJumpIfFalse:
+			offset := readShort()
+			if !isTruthy(vm.peek(0)) {
+				frame.ip += int(offset)
+			}
+		case compiler.OpLoop:
+			offset := readShort()
+			frame.ip -= int(offset)
+		case compiler.OpCall:
+			argCount := int(readByte())
+			if err := vm.callValue(vm.peek(argCount), argCount); err != nil {
+				return nil, err
+			}
+
+			frame = &vm.frames[vm.frameCount-1]
+			chunk = &frame.closure.Function.Chunk
+		case compiler.OpClosure:
+			fn := chunk.Constants[readShort()].(*compiler.Function)
+			closure := &Closure{fn, make([]*Upvalue, fn.UpvalueCount)}
+			for i := range closure.upvalues {
+				isLocal := readByte() == 1
+				index := int(readByte())
+
+				if isLocal {
+					closure.upvalues[i] = vm.captureUpvalue(frame.base + index)
+				} else {
+					closure.upvalues[i] = frame.closure.upvalues[index]
+				}
+			}
+
+			if err := vm.push(closure); err != nil {
+				return nil, err
+			}
+		case compiler.OpCloseUpvalue:
+			vm.closeUpvalues(vm.sp - 1)
+			vm.sp--
+		case compiler.OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(frame.base)
+
+			vm.frameCount--
+			if vm.frameCount == 0 {
+				vm.sp--
+				return result, nil
+			}
+
+			vm.sp = frame.base
+			if err := vm.push(result); err != nil {
+				return nil, err
+			}
+
+			frame = &vm.frames[vm.frameCount-1]
+			chunk = &frame.closure.Function.Chunk
+
+		case compiler.OpClass:
+			if err := vm.push(&Class{Name: readString(), methods: make(map[string]*Closure)}); err != nil {
+				return nil, err
+			}
+		case compiler.OpInherit:
+			class := vm.peek(0).(*Class)
+			super, ok := vm.peek(1).(*Class)
+			if !ok {
+				return nil, vm.runtimeError("superclass of %s must be a class", class.Name)
+			}
+
+			for name, method := range super.methods {
+				class.methods[name] = method
+			}
+
+			vm.sp--
+		case compiler.OpMethod:
+			method := vm.pop().(*Closure)
+			vm.peek(0).(*Class).methods[readString()] = method
+
+		case compiler.OpList:
+			count := int(readShort())
+			elements := make([]any, count)
+			copy(elements, vm.stack[vm.sp-count:vm.sp])
+
+			vm.sp -= count
+			if err := vm.push(lox.NewList(elements)); err != nil {
+				return nil, err
+			}
+		case compiler.OpMap:
+			count := int(readShort())
+			entries := vm.stack[vm.sp-2*count : vm.sp]
+
+			m := lox.NewMap()
+			for i := 0; i < len(entries); i += 2 {
+				if err := m.Set(entries[i], entries[i+1]); err != nil {
+					return nil, vm.runtimeError("%s", err.Error())
+				}
+			}
+
+			vm.sp -= 2 * count
+			if err := vm.push(m); err != nil {
+				return nil, err
+			}
+		case compiler.OpGetIndex:
+			val, err := lox.Index(vm.peek(1), vm.pop())
+			if err != nil {
+				return nil, vm.runtimeError("%s", err.Error())
+			}
+
+			vm.stack[vm.sp-1] = val
+		case compiler.OpSetIndex:
+			val := vm.pop()
+			if err := lox.SetIndex(vm.peek(1), vm.pop(), val); err != nil {
+				return nil, vm.runtimeError("%s", err.Error())
+			}
+
+			vm.stack[vm.sp-1] = val
+
+		default:
+			return nil, vm.runtimeError("unknown opcode %d", op)
+		}
+	}
+}
+
+var opLexemes = map[compiler.OpCode]string{
+	compiler.OpGreater:      ">",
+	compiler.OpGreaterEqual: ">=",
+	compiler.OpLess:         "<",
+	compiler.OpLessEqual:    "<=",
+	compiler.OpSubtract:     "-",
+	compiler.OpMultiply:     "*",
+	compiler.OpDivide:       "/",
+}
+
+func arithmetic(op compiler.OpCode, l, r float32) any {
+	switch op {
+	case compiler.OpGreater:
+		return l > r
+	case compiler.OpGreaterEqual:
+		return l >= r
+	case compiler.OpLess:
+		return l < r
+	case compiler.OpLessEqual:
+		return l <= r
+	case compiler.OpSubtract:
+		return l - r
+	case compiler.OpMultiply:
+		return l * r
+	default:
+		return l / r
+	}
+}
+
+func (vm *VM) callValue(callee any, argCount int) error {
+	switch callee := callee.(type) {
+	case *Closure:
+		return vm.call(callee, argCount)
+	case *BoundMethod:
+		vm.stack[vm.sp-argCount-1] = callee.receiver
+		return vm.call(callee.method, argCount)
+	case *Class:
+		vm.stack[vm.sp-argCount-1] = &Instance{callee, make(map[string]any)}
+		if init, ok := callee.methods["init"]; ok {
+			return vm.call(init, argCount)
+		}
+
+		if argCount != 0 {
+			return vm.runtimeError("expected 0 arguments, got %d", argCount)
+		}
+	case *Native:
+		if argCount != int(callee.Arity) {
+			return vm.runtimeError("expected %d arguments, got %d", callee.Arity, argCount)
+		}
+
+		result, err := callee.fn(vm, vm.stack[vm.sp-argCount:vm.sp])
+		if err != nil {
+			return vm.runtimeError("%s", err.Error())
+		}
+
+		vm.sp -= argCount + 1
+		return vm.push(result)
+	default:
+		return vm.runtimeError("callee is not callable")
+	}
+
+	return nil
+}
+
+func (vm *VM) call(closure *Closure, argCount int) error {
+	if argCount != int(closure.Function.Arity) {
+		return vm.runtimeError("expected %d arguments, got %d", closure.Function.Arity, argCount)
+	}
+
+	if vm.frameCount == framesMax {
+		return vm.runtimeError("stack overflow")
+	}
+
+	vm.frames[vm.frameCount] = frame{closure, 0, vm.sp - argCount - 1}
+	vm.frameCount++
+
+	return nil
+}
+
+// captureUpvalue reuses the open upvalue of the slot if there is one,
+// open upvalues are sorted by slot, the topmost first
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	var prev *Upvalue
+	up := vm.openUpvalues
+	for up != nil && up.slot > slot {
+		prev = up
+		up = up.next
+	}
+
+	if up != nil && up.slot == slot {
+		return up
+	}
+
+	created := &Upvalue{slot: slot, next: up}
+	if prev == nil {
+		vm.openUpvalues = created
+	} else {
+		prev.next = created
+	}
+
+	return created
+}
+
+// closeUpvalues moves every variable at or above
+// the slot off the stack into its upvalue
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= last {
+		up := vm.openUpvalues
+		up.closed = vm.stack[up.slot]
+		up.slot = -1
+		vm.openUpvalues = up.next
+	}
+}
+
+func (vm *VM) push(value any) error {
+	if vm.sp == stackMax {
+		return vm.runtimeError("stack overflow")
+	}
+
+	vm.stack[vm.sp] = value
+	vm.sp++
+	return nil
+}
+
+func (vm *VM) pop() any {
+	vm.sp--
+	return vm.stack[vm.sp]
+}
+
+func (vm *VM) peek(distance int) any {
+	return vm.stack[vm.sp-1-distance]
+}
+
+func (vm *VM) runtimeError(format string, args ...any) error {
+	var tok token.Token
+	frames := make([]lox.Frame, 0, vm.frameCount)
+	for i := vm.frameCount - 1; i >= 0; i-- {
+		frame := &vm.frames[i]
+		function := frame.closure.Function
+
+		name := function.Name
+		if name == "" {
+			name = "script"
+		}
+
+		at := function.Chunk.Tokens[max(frame.ip-1, 0)]
+		if i == vm.frameCount-1 {
+			tok = at
+		}
+
+		frames = append(frames, lox.Frame{Function: name, File: at.File, Line: at.Line})
+	}
+
+	return &RuntimeError{Token: tok, Message: fmt.Sprintf(format, args...), Frames: frames}
+}
@@ -0,0 +1,272 @@
+package vm
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/havrydotdev/golox/compiler"
+	"github.com/havrydotdev/golox/lox"
+	"github.com/havrydotdev/golox/parser"
+	"github.com/havrydotdev/golox/scanner"
+)
+
+func run(t *testing.T, source string) (*VM, error) {
+	t.Helper()
+
+	tokens, errs := scanner.New(source).Scan()
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	comp := compiler.New()
+	code, errs := parser.New(tokens, comp).Parse()
+	for _, err := range errs {
+		t.Fatal(err)
+	}
+
+	script, errs := comp.Compile(code)
+	for _, err := range errs {
+		t.Fatal(err)
+	}
+
+	vm := New()
+	_, err := vm.Run(script)
+
+	return vm, err
+}
+
+func expectGlobal(t *testing.T, vm *VM, name string, expected any) {
+	t.Helper()
+
+	val, ok := vm.globals[name]
+	if !ok {
+		t.Fatalf("global %s is not defined", name)
+	}
+
+	if val != expected {
+		t.Errorf("expected %s to be %v, got %v", name, expected, val)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	vm, err := run(t, `
+fun makeCounter() {
+  var i = 0;
+  fun get() { return i; }
+  fun inc() { i = i + 1; }
+
+  fun counter(op) {
+    if (op == "inc") inc();
+    return get();
+  }
+
+  return counter;
+}
+
+var counter = makeCounter();
+counter("inc");
+counter("inc");
+var count = counter("get");
+
+var a = "global";
+var first;
+var second;
+{
+  fun showA() { return a; }
+  first = showA();
+  var a = "block";
+  second = showA();
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectGlobal(t, vm, "count", float32(2))
+	expectGlobal(t, vm, "first", "global")
+	expectGlobal(t, vm, "second", "global")
+}
+
+func TestClasses(t *testing.T) {
+	vm, err := run(t, `
+class A {
+  init(name) { this.name = name; }
+  greet() { return "hello from " + this.name; }
+}
+
+class B < A {
+  init() { super.init("B"); }
+  greet() { return super.greet() + "!"; }
+}
+
+var b = B();
+var greeting = b.greet();
+var method = b.greet;
+var bound = method();
+var same = b.init() == b;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectGlobal(t, vm, "greeting", "hello from B!")
+	expectGlobal(t, vm, "bound", "hello from B!")
+	expectGlobal(t, vm, "same", true)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := map[string]string{
+		"not callable":   "var a = 1; a();",
+		"arity":          "fun f(a) {} f();",
+		"undefined":      "print(b);",
+		"not a class":    "var A = 1; class B < A {}",
+		"operands":       "var a = 1 - \"a\";",
+		"stack overflow": "fun f() { f(); } f();",
+		// the elements are on the stack until the list is made
+		"stack slots": "print([nil" + strings.Repeat(", nil", math.MaxUint16-1) + "]);",
+	}
+
+	for name, source := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := run(t, source); err == nil {
+				t.Error("expected runtime error")
+			}
+		})
+	}
+}
+
+func TestBreakContinue(t *testing.T) {
+	vm, err := run(t, `
+var sum = 0;
+for (var i = 0; i < 10; i = i + 1) {
+  var skip = i == 3;
+  fun captured() { return skip; }
+  if (skip) continue;
+  if (i == 6) break;
+  sum = sum + i;
+}
+
+var loops = 0;
+while (true) {
+  {
+    var local = 1;
+    loops = loops + local;
+    if (loops < 4) continue;
+  }
+
+  break;
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectGlobal(t, vm, "sum", float32(0+1+2+4+5))
+	expectGlobal(t, vm, "loops", float32(4))
+}
+
+func TestLists(t *testing.T) {
+	vm, err := run(t, `
+var xs = [1, 2, [3]];
+xs[0] = -xs[0];
+xs[2][0] = 4;
+push(xs, 5);
+var popped = pop(xs);
+var length = len(xs);
+var first = xs[0];
+var nested = xs[2][0];
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectGlobal(t, vm, "popped", float32(5))
+	expectGlobal(t, vm, "length", float32(3))
+	expectGlobal(t, vm, "first", float32(-1))
+	expectGlobal(t, vm, "nested", float32(4))
+}
+
+func TestMaps(t *testing.T) {
+	vm, err := run(t, `
+class A {}
+var a = A();
+var m = {"one": 1, 2: "two"};
+m["one"] = m["one"] + 10;
+m[a] = "instance";
+var removed = delete(m, 2);
+var length = len(m);
+var one = m["one"];
+var byIdentity = m[a];
+var hasOther = has(m, A());
+var lastKey = keys(m)[1];
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectGlobal(t, vm, "removed", true)
+	expectGlobal(t, vm, "length", float32(2))
+	expectGlobal(t, vm, "one", float32(11))
+	expectGlobal(t, vm, "byIdentity", "instance")
+	expectGlobal(t, vm, "hasOther", false)
+
+	if key, _ := vm.globals["lastKey"].(*Instance); key == nil {
+		t.Errorf("expected the instance key, got %v", vm.globals["lastKey"])
+	}
+}
+
+func TestLambdas(t *testing.T) {
+	vm, err := run(t, `
+fun counter() {
+  var n = 0;
+  return fun () { n = n + 1; return n; };
+}
+
+var next = counter();
+next();
+var second = next();
+var squared = fun (x) { return x * x; }(3);
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectGlobal(t, vm, "second", float32(2))
+	expectGlobal(t, vm, "squared", float32(9))
+}
+
+func TestStackTrace(t *testing.T) {
+	_, err := run(t, `
+fun inner() {
+  return 1 + nil;
+}
+
+fun outer() {
+  return inner();
+}
+
+outer();
+`)
+
+	var rerr *RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected runtime error, got %v", err)
+	}
+
+	expected := []lox.Frame{
+		{Function: "inner", Line: 3},
+		{Function: "outer", Line: 7},
+		{Function: "script", Line: 10},
+	}
+	if !reflect.DeepEqual(rerr.Frames, expected) {
+		t.Errorf("expected frames %v, got %v", expected, rerr.Frames)
+	}
+
+	// the error points at the operator, not just the line
+	if rerr.Token.Lexeme != "+" || rerr.Token.Line != 3 || rerr.Token.Column != 12 {
+		t.Errorf("expected the error at 3:12 '+', got %v", rerr.Token)
+	}
+}